@@ -1,84 +1,66 @@
 package main
 
 import (
-	"database/sql"
 	"os"
-	"os/exec"
 )
 
 // See if Docker is installed
 func (a *App) IsDockerInstalled() bool {
-	// Add +"usr/local/bin:" to the PATH
-	os.Setenv("PATH", "/usr/local/bin:"+os.Getenv("PATH"))
-
-	// Add to the PATH the location of the docker and docker-compose executables from the database
-	db, err := sql.Open("sqlite3", databasePath)
-	if err == nil {
-		defer db.Close()
-		// Query the database for the path to the docker and docker-compose executables
-		rows, err := db.Query("SELECT path FROM platform_paths WHERE platform = ?", "docker")
-		if err == nil {
-			defer rows.Close()
-			// If the query matched a row, add the path to the PATH
-			if rows.Next() {
-				var path string
-				if err = rows.Scan(&path); err == nil {
-					os.Setenv("PATH", path+":"+os.Getenv("PATH"))
-				}
-			}
-		}
-	}
+	a.addPlatformPath("docker")
 
 	// Check if docker is installed
-	// Run "docker compose --version", if it fails, run "docker-compose --version"
-	// command := exec.Command("docker", "compose", "--version")
-	// err = command.Run()
-	_, err = RunCommand(exec.Command("docker", "compose", "--version"))
-	if err != nil {
-		// command = exec.Command("docker-compose", "--version")
-		// err = command.Run()
-		_, err = RunCommand(exec.Command("docker-compose", "--version"))
-		if err != nil {
-			return false
-		}
-	}
-	return true
+	return isDockerComposeInstalled()
 }
 
 func (a *App) IsDockerRunning() bool {
 	// Run the command to see if docker is running
-	// command := exec.Command("docker", "info")
-	// _, err := command.Output()
-	_, err := RunCommand(exec.Command("docker", "info"))
-	return err == nil
+	return isDockerRunning()
 }
 
 // See if Kubernetes is installed
 func (a *App) IsKubernetesInstalled() bool {
+	a.addPlatformPath("kubernetes")
+
+	// Run the command to see if kubectl is installed
+	return isKubectlInstalled()
+}
+
+// See if Podman is installed, with podman compose or podman-compose
+func (a *App) IsPodmanInstalled() bool {
+	a.addPlatformPath("podman")
+
+	// Check if podman and a compose provider are installed
+	if !isPodmanInstalled() {
+		return false
+	}
+	_, _, err := getPodmanComposeCommand()
+	return err == nil
+}
+
+func (a *App) IsPodmanRunning() bool {
+	// Run the command to see if podman can run containers
+	return isPodmanRunning()
+}
+
+// Check if everything needed to install environments on the given platform is available, returns an error describing what is missing
+func (a *App) CheckPlatform(platform string) error {
+	driver, err := getPlatformDriver(platform)
+	if err != nil {
+		return err
+	}
+
+	a.addPlatformPath(platform)
+	return driver.Prerequisites()
+}
+
+// Add to the PATH the folders where the executables of the platform may be located
+func (a *App) addPlatformPath(platform string) {
 	// Add +"usr/local/bin:" to the PATH
 	os.Setenv("PATH", "/usr/local/bin:"+os.Getenv("PATH"))
 
-	// Add to the PATH the location of the kubectl executable from the database
-	db, err := sql.Open("sqlite3", databasePath)
-	if err == nil {
-		defer db.Close()
-		// Query the database for the path to the kubectl executable
-		rows, err := db.Query("SELECT path FROM platform_paths WHERE platform = ?", "kubernetes")
-		if err == nil {
-			defer rows.Close()
-			// If the query matched a row, add the path to the PATH
-			if rows.Next() {
-				var path string
-				if err = rows.Scan(&path); err == nil {
-					os.Setenv("PATH", path+":"+os.Getenv("PATH"))
-				}
-			}
-		}
+	// Add to the PATH the location of the executables of the platform from the store
+	path, err := a.store.GetPlatformPath(platform)
+	if err == nil && path != "" {
+		os.Setenv("PATH", path+":"+os.Getenv("PATH"))
 	}
-
-	// Run the command to see if kubectl is installed
-	// command := exec.Command("kubectl", "version", "--client")
-	// _, err = command.Output()
-	_, err = RunCommand(exec.Command("kubectl", "version", "--client"))
-	return err == nil
 }
@@ -0,0 +1,1200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Exit codes returned by the headless CLI
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// The subcommands available in headless mode and a short description for the usage message
+var cliCommands = []struct {
+	name        string
+	description string
+	run         func(a *App, out io.Writer, args []string) error
+}{
+	{"install", "Install a new environment (or update an existing one with --edit)", cliInstall},
+	{"list", "List the installed environments", cliList},
+	{"delete", "Delete an installed environment", cliDelete},
+	{"stop", "Stop the services of an environment, keeping its data", cliStop},
+	{"start", "Start the services of a stopped environment", cliStart},
+	{"restart", "Restart the services of an environment, or only one with --service", cliRestart},
+	{"prune", "Forget the environments that were removed from their platform outside of the application", cliPrune},
+	{"populate", "Populate an installed environment with the metadata files in a folder", cliPopulate},
+	{"export", "Save the definition of an installed environment to a file", cliExport},
+	{"import", "Install an environment from a file saved by export", cliImport},
+	{"upgrade", "Update the variables of an environment to the defaults of this version and install it again", cliUpgrade},
+	{"status", "Show the state of the services of an environment and check its access points", cliStatus},
+	{"logs", "Print the logs of a service of an environment, or save the logs of all of them in a zip file", cliLogs},
+	{"history", "List the operations run on an environment, or the last ones of all of them with --all", cliHistory},
+	{"log", "Print the output of an operation of the history", cliLog},
+	{"contexts", "List the available kubernetes contexts", cliContexts},
+	{"hosts", "List the addresses of this machine that can be given to install --host", cliHosts},
+	{"ports", "Show the ports reserved by the environments, or change the range of the assigned ports", cliPorts},
+	{"check", "Check if the installation platforms are available", cliCheck},
+	{"export-images", "Pull the images of a platform and save them in a tarball, to install without internet", cliExportImages},
+	{"load-images", "Load the images of a tarball saved by export-images, or push them to a registry for kubernetes", cliLoadImages},
+	{"connectivity", "Check that the registries and the releases of the application can be reached", cliConnectivity},
+	{"network", "Show or change the proxy and the certificate authorities used by the application", cliNetwork},
+	{"offline", "Show, enable or disable the offline mode: installs only use the loaded images", cliOffline},
+	{"diagnostics", "Save what is needed to look into a problem in a zip file, to send it with a support request", cliDiagnostics},
+	{"update", "Update the application to the latest release", cliUpdate},
+}
+
+// errUsage is returned by a subcommand when it was called with invalid arguments
+type errUsage struct {
+	message string
+}
+
+func (e errUsage) Error() string {
+	return e.message
+}
+
+// Check if the arguments given to the executable should start the headless CLI instead of the window.
+// Only the known commands do, the other arguments are left to the window, e.g. the -psn_ argument given by macOS
+// to the apps opened from the Finder or the path of a file opened with the application.
+func isCLIInvocation(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "help", "-h", "--help", operationWorkerCommand:
+		return true
+	}
+	for _, command := range cliCommands {
+		if command.name == args[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// NewHeadlessApp creates an App using the given store that writes the events it would send to the frontend to out.
+// The operations it runs are cancelled when ctx is done
+func NewHeadlessApp(ctx context.Context, store EnvironmentStore, out io.Writer) *App {
+	return &App{
+		ctx:   ctx,
+		store: store,
+		emit: func(eventName string, data ...interface{}) {
+			// Only the terminal output is meaningful outside of the window, the line is followed by the operation id
+			if eventName != terminalOutputEventName || len(data) == 0 {
+				return
+			}
+			fmt.Fprintln(out, data[0])
+		},
+	}
+}
+
+// Run the headless CLI with the given arguments (without the executable name) and return the exit code
+func runCLI(args []string) int {
+	stdout := os.Stdout
+	stderr := os.Stderr
+
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printCLIUsage(stdout)
+		return exitOK
+	}
+
+	// The worker process of an install, populate or delete started by the app
+	if args[0] == operationWorkerCommand {
+		return runOperationWorker()
+	}
+
+	// Find the subcommand
+	for _, command := range cliCommands {
+		if command.name != args[0] {
+			continue
+		}
+
+		// Initialize the database, as it would be done by the startup of the window
+		store, err := databaseInit()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error initializing the database: %v\n", err)
+			return exitError
+		}
+		defer store.Close()
+
+		// Cancel the running install, populate or delete on ctrl+c, the cleanup is done before returning
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		app := NewHeadlessApp(ctx, store, stdout)
+		app.loadNetworkSettings()
+		err = command.run(app, stdout, args[1:])
+		if err == nil {
+			return exitOK
+		}
+		if err == flag.ErrHelp {
+			return exitUsage
+		}
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		if _, ok := err.(errUsage); ok {
+			return exitUsage
+		}
+		return exitError
+	}
+
+	fmt.Fprintf(stderr, "Unknown command: %s\n\n", args[0])
+	printCLIUsage(stderr)
+	return exitUsage
+}
+
+// Print the list of the available subcommands
+func printCLIUsage(out io.Writer) {
+	fmt.Fprintf(out, "EPOS Data Portal Installer %s\n\n", VERSION)
+	fmt.Fprintln(out, "Run without arguments to open the application window, or use one of the commands below.")
+	fmt.Fprintln(out, "")
+	fmt.Fprintln(out, "Commands:")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, command := range cliCommands {
+		fmt.Fprintf(w, "  %s\t%s\n", command.name, command.description)
+	}
+	w.Flush()
+	fmt.Fprintln(out, "")
+	fmt.Fprintln(out, "Use \"<command> -h\" for the options of a command.")
+}
+
+// Create the flag set for a subcommand
+func newCLIFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	return flags
+}
+
+// keyValueFlags collects repeated KEY=VALUE flags
+type keyValueFlags map[string]string
+
+func (f keyValueFlags) String() string {
+	var pairs []string
+	for key, value := range f {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f keyValueFlags) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return fmt.Errorf("expected KEY=VALUE, got %q", value)
+	}
+	f[strings.TrimSpace(parts[0])] = parts[1]
+	return nil
+}
+
+// The usage message of the --platform flag
+const platformFlagUsage = "installation platform (docker, kubernetes or podman)"
+
+// Check that the platform flag has a supported value
+func validateCLIPlatform(platform string) error {
+	if _, err := getPlatformDriver(platform); err != nil {
+		return errUsage{fmt.Sprintf("--platform must be one of %s, got %q", strings.Join(getPlatformNames(), ", "), platform)}
+	}
+	return nil
+}
+
+// Check that all the required flags have been set
+func requireCLIFlags(flags *flag.FlagSet, names ...string) error {
+	for _, name := range names {
+		if flags.Lookup(name).Value.String() == "" {
+			return errUsage{fmt.Sprintf("the --%s flag is required", name)}
+		}
+	}
+	return nil
+}
+
+// The flags selecting an installed environment, by its id or by its name, version, platform and context
+type environmentFlags struct {
+	id          *string
+	platform    *string
+	name        *string
+	version     *string
+	kubeContext *string
+}
+
+func addEnvironmentFlags(flags *flag.FlagSet) environmentFlags {
+	return environmentFlags{
+		id:          flags.String("id", "", "id of the environment, as printed by the list command (instead of the other flags)"),
+		platform:    flags.String("platform", "docker", platformFlagUsage),
+		name:        flags.String("name", "", "name of the environment"),
+		version:     flags.String("version", "", "version of the environment"),
+		kubeContext: flags.String("context", "", "kubernetes context of the environment"),
+	}
+}
+
+// Get the environment selected by the flags
+func (f environmentFlags) find(a *App) (Environment, error) {
+	if *f.id != "" {
+		return a.store.GetEnvironment(*f.id)
+	}
+	if err := validateCLIPlatform(*f.platform); err != nil {
+		return Environment{}, err
+	}
+	if *f.name == "" || *f.version == "" {
+		return Environment{}, errUsage{"the --id flag or the --name and --version flags are required"}
+	}
+	if *f.platform == "kubernetes" && *f.kubeContext == "" {
+		return Environment{}, errUsage{"the --context flag is required for kubernetes environments"}
+	}
+	return a.store.FindEnvironment(*f.name, *f.version, *f.platform, *f.kubeContext)
+}
+
+func cliInstall(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("install")
+	platform := flags.String("platform", "docker", platformFlagUsage)
+	name := flags.String("name", "", "name of the environment (the namespace on kubernetes)")
+	version := flags.String("version", "", "version of the environment")
+	kubeContext := flags.String("context", "", "kubernetes context to install the environment into")
+	host := flags.String("host", "", "address of this machine in the access points, see hosts (the default route if empty, the saved one with --edit)")
+	envFile := flags.String("env-file", "", "file with KEY=VALUE lines overriding the default variables")
+	skipImagesAutoupdate := flags.Bool("skip-images-autoupdate", false, "do not check for newer images before installing")
+	isEdit := flags.Bool("edit", false, "update an environment that is already installed")
+	assignPorts := flags.Bool("assign-ports", false, "replace the ports used by other environments or programs with free ones of the port range")
+	overrides := keyValueFlags{}
+	flags.Var(overrides, "var", "override a variable as KEY=VALUE (can be repeated)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := validateCLIPlatform(*platform); err != nil {
+		return err
+	}
+	if err := requireCLIFlags(flags, "name", "version"); err != nil {
+		return err
+	}
+	if *platform == "kubernetes" && *kubeContext == "" {
+		return errUsage{"the --context flag is required for kubernetes environments"}
+	}
+
+	// Start from the variables of the installed environment when editing, from the defaults otherwise
+	var variables []Section
+	environmentId := ""
+	if *isEdit {
+		environment, err := a.store.FindEnvironment(*name, *version, *platform, *kubeContext)
+		if err != nil {
+			return err
+		}
+		variables = environment.Variables
+		environmentId = environment.ID
+		if *host == "" {
+			*host = environment.EnvironmentSetup.Host
+		}
+	} else {
+		defaults, err := a.ReadEnvVariables(*platform)
+		if err != nil {
+			return err
+		}
+		variables = defaults
+	}
+
+	// Apply the overrides, the ones given with --var take precedence over the env file
+	if *envFile != "" {
+		content, err := os.ReadFile(*envFile)
+		if err != nil {
+			return err
+		}
+		fileOverrides, err := parseKeyValueFile(content)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *envFile, err)
+		}
+		if err := overrideVariables(variables, fileOverrides); err != nil {
+			return err
+		}
+	}
+	if err := overrideVariables(variables, overrides); err != nil {
+		return err
+	}
+	if *assignPorts {
+		assigned, err := a.AssignPorts(environmentId, *platform, variables)
+		if err != nil {
+			return err
+		}
+		variables = assigned
+	}
+
+	environmentSetup := EnvironmentSetup{Name: *name, Version: *version, Context: *kubeContext, Host: *host}
+	if !*isEdit && a.IsEnvironmentInstalled(*name, *version, *platform, *kubeContext) {
+		return fmt.Errorf("environment %s %s is already installed, use --edit to update it", *name, *version)
+	}
+
+	id, err := a.InstallEnvironment(*platform, environmentSetup, variables, *skipImagesAutoupdate, *isEdit)
+	if err != nil {
+		return err
+	}
+	return printAccessPoints(a, out, id)
+}
+
+// Print the id and the access points of an installed environment
+func printAccessPoints(a *App, out io.Writer, id string) error {
+	environment, err := a.store.GetEnvironment(id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Environment id: %s\n", environment.ID)
+	fmt.Fprintf(out, "Data portal: %s\n", environment.AccessPoints.DataPortal)
+	fmt.Fprintf(out, "API gateway: %s\n", environment.AccessPoints.ApiGateway)
+	return nil
+}
+
+// Parse a file with KEY=VALUE lines, ignoring empty lines and comments
+func parseKeyValueFile(content []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d is not a KEY=VALUE pair", i+1)
+		}
+		values[strings.TrimSpace(parts[0])] = strings.Trim(strings.TrimSpace(parts[1]), "\"")
+	}
+	return values, nil
+}
+
+// Set the given values in the sections, failing on variables that are not part of any section
+func overrideVariables(sections []Section, values map[string]string) error {
+	for key, value := range values {
+		found := false
+		for _, section := range sections {
+			if _, ok := section.Variables[key]; ok {
+				section.Variables[key] = value
+				found = true
+			}
+		}
+		if !found {
+			return errUsage{fmt.Sprintf("unknown variable: %s", key)}
+		}
+	}
+	return nil
+}
+
+func cliList(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("list")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	environments, err := a.GetInstalledEnvironments()
+	if err != nil {
+		return err
+	}
+	if len(environments) == 0 {
+		fmt.Fprintln(out, "No environments installed")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tVERSION\tPLATFORM\tCONTEXT\tSTATUS\tDATA PORTAL\tAPI GATEWAY")
+	for _, environment := range environments {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			environment.ID,
+			environment.EnvironmentSetup.Name,
+			environment.EnvironmentSetup.Version,
+			environment.Platform,
+			environment.EnvironmentSetup.Context,
+			environment.Status,
+			environment.AccessPoints.DataPortal,
+			environment.AccessPoints.ApiGateway,
+		)
+	}
+	return w.Flush()
+}
+
+func cliDelete(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("delete")
+	selected := addEnvironmentFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	environment, err := selected.find(a)
+	if err != nil {
+		return err
+	}
+
+	return a.DeleteInstalledEnvironment(environment.ID)
+}
+
+func cliStop(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("stop")
+	selected := addEnvironmentFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	environment, err := selected.find(a)
+	if err != nil {
+		return err
+	}
+
+	return a.StopEnvironment(environment.ID)
+}
+
+func cliStart(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("start")
+	selected := addEnvironmentFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	environment, err := selected.find(a)
+	if err != nil {
+		return err
+	}
+
+	return a.StartEnvironment(environment.ID)
+}
+
+func cliRestart(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("restart")
+	selected := addEnvironmentFlags(flags)
+	service := flags.String("service", "", "only restart this service, as listed by the status command")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	environment, err := selected.find(a)
+	if err != nil {
+		return err
+	}
+
+	if *service != "" {
+		return a.RestartService(environment.ID, *service)
+	}
+	return a.RestartEnvironment(environment.ID)
+}
+
+func cliPrune(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("prune")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	pruned, err := a.PruneMissingEnvironments()
+	for _, environment := range pruned {
+		fmt.Fprintf(out, "Removed %s %s (%s)\n", environment.EnvironmentSetup.Name, environment.EnvironmentSetup.Version, environment.ID)
+	}
+	if err != nil {
+		return err
+	}
+	if len(pruned) == 0 {
+		fmt.Fprintln(out, "No missing environments")
+	}
+	return nil
+}
+
+func cliPopulate(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("populate")
+	selected := addEnvironmentFlags(flags)
+	path := flags.String("path", "", "folder with the metadata files to load")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := requireCLIFlags(flags, "path"); err != nil {
+		return err
+	}
+	environment, err := selected.find(a)
+	if err != nil {
+		return err
+	}
+
+	// The populate commands expect the path of a folder with a trailing separator
+	info, err := os.Stat(*path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errUsage{fmt.Sprintf("%s is not a folder", *path)}
+	}
+	folder := *path
+	if !strings.HasSuffix(folder, string(os.PathSeparator)) {
+		folder += string(os.PathSeparator)
+	}
+
+	return a.PopulateEnvironment(environment.ID, folder)
+}
+
+func cliExport(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("export")
+	selected := addEnvironmentFlags(flags)
+	path := flags.String("path", "", "file to write the environment to")
+	includeSecrets := flags.Bool("include-secrets", false, "also write the passwords and keys, in clear")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := requireCLIFlags(flags, "path"); err != nil {
+		return err
+	}
+
+	environment, err := selected.find(a)
+	if err != nil {
+		return err
+	}
+	return writeEnvironmentFile(*path, environment, *includeSecrets)
+}
+
+func cliImport(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("import")
+	path := flags.String("path", "", "environment file written by export")
+	name := flags.String("name", "", "install the environment with another name")
+	version := flags.String("version", "", "install the environment with another version")
+	kubeContext := flags.String("context", "", "install the environment into another kubernetes context")
+	skipImagesAutoupdate := flags.Bool("skip-images-autoupdate", false, "do not check for newer images before installing")
+	defaultSecrets := flags.Bool("default-secrets", false, "install the secrets missing from the file with their default values")
+	overrides := keyValueFlags{}
+	flags.Var(overrides, "var", "override a variable as KEY=VALUE, e.g. the secrets missing from the file (can be repeated)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := requireCLIFlags(flags, "path"); err != nil {
+		return err
+	}
+
+	environment, missing, err := a.readEnvironmentFile(*path)
+	if err != nil {
+		return err
+	}
+	if err := overrideVariables(environment.Variables, overrides); err != nil {
+		return err
+	}
+	if !*defaultSecrets {
+		if err := setMissingSecrets(environment.Variables, missing, overrides); err != nil {
+			return errUsage{err.Error() + ", give them with --var or use --default-secrets"}
+		}
+	}
+	setup := environment.EnvironmentSetup
+	if *name != "" {
+		setup.Name = *name
+	}
+	if *version != "" {
+		setup.Version = *version
+	}
+	if *kubeContext != "" {
+		setup.Context = *kubeContext
+	}
+	if a.IsEnvironmentInstalled(setup.Name, setup.Version, environment.Platform, setup.Context) {
+		return fmt.Errorf("environment %s %s is already installed", setup.Name, setup.Version)
+	}
+
+	id, err := a.InstallEnvironment(environment.Platform, setup, environment.Variables, *skipImagesAutoupdate, false)
+	if err != nil {
+		return err
+	}
+	return printAccessPoints(a, out, id)
+}
+
+func cliUpgrade(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("upgrade")
+	selected := addEnvironmentFlags(flags)
+	dryRun := flags.Bool("dry-run", false, "only print the changes to the variables")
+	skipImagesAutoupdate := flags.Bool("skip-images-autoupdate", false, "do not check for newer images before installing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	environment, err := selected.find(a)
+	if err != nil {
+		return err
+	}
+
+	diff, err := a.DiffEnvironmentVariables(environment.ID)
+	if err != nil {
+		return err
+	}
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0 {
+		fmt.Fprintln(out, "The variables of the environment are up to date")
+		return nil
+	}
+
+	for _, change := range diff.Added {
+		fmt.Fprintf(out, "+ %s=%s\n", change.Variable, change.NewDefault)
+	}
+	for _, change := range diff.Removed {
+		fmt.Fprintf(out, "- %s=%s\n", change.Variable, change.Value)
+	}
+	for _, change := range diff.Changed {
+		if change.Customized {
+			fmt.Fprintf(out, "  %s=%s (kept, the default changed from %s to %s)\n", change.Variable, change.Value, change.OldDefault, change.NewDefault)
+		} else {
+			fmt.Fprintf(out, "~ %s=%s (was %s)\n", change.Variable, change.NewDefault, change.OldDefault)
+		}
+	}
+	if *dryRun {
+		return nil
+	}
+
+	return a.UpgradeEnvironment(environment.ID, *skipImagesAutoupdate)
+}
+
+func cliStatus(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("status")
+	selected := addEnvironmentFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	environment, err := selected.find(a)
+	if err != nil {
+		return err
+	}
+
+	status, err := a.GetEnvironmentStatus(environment.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Status: %s\n", status.Status)
+	for _, probe := range []struct {
+		name  string
+		probe EndpointProbe
+	}{{"Data Portal", status.DataPortal}, {"API Gateway", status.ApiGateway}} {
+		result := probe.probe.Error
+		if result == "" {
+			result = fmt.Sprintf("%d in %dms", probe.probe.StatusCode, probe.probe.DurationMs)
+		}
+		fmt.Fprintf(out, "%s: %s (%s)\n", probe.name, probe.probe.URL, result)
+	}
+	if status.ServicesError != "" {
+		fmt.Fprintf(out, "Services: %s\n", status.ServicesError)
+	}
+	if len(status.Services) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(out)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tNAME\tSTATE\tHEALTH\tRESTARTS\tIMAGE")
+	for _, service := range status.Services {
+		health := service.Health
+		if health == "" {
+			health = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n",
+			service.Service,
+			service.Name,
+			service.State,
+			health,
+			service.Restarts,
+			service.Image,
+		)
+	}
+	return w.Flush()
+}
+
+func cliLogs(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("logs")
+	selected := addEnvironmentFlags(flags)
+	service := flags.String("service", "", "service to print the logs of, as listed by the status command")
+	follow := flags.Bool("follow", false, "keep printing the new lines until Ctrl+C is pressed")
+	tail := flags.Int("tail", 0, "only print the last lines")
+	bundle := flags.String("bundle", "", "save the logs of all the services and the variables of the environment in this zip file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if (*service == "") == (*bundle == "") {
+		return errUsage{"one of --service or --bundle is required"}
+	}
+	environment, err := selected.find(a)
+	if err != nil {
+		return err
+	}
+
+	if *bundle != "" {
+		return writeLogsBundle(a.ctx, *bundle, environment)
+	}
+
+	output := newOperationOutput("", func(_ string, data ...interface{}) {
+		fmt.Fprintln(out, data[0])
+	}, nil, nil)
+	output.secrets = secretValuesOf(environment.Variables)
+	err = writeServiceLogs(a.ctx, environment, *service, *follow, *tail, output)
+	output.Close()
+	// Ctrl+C is the way to stop following the logs
+	if a.ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func cliHistory(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("history")
+	selected := addEnvironmentFlags(flags)
+	all := flags.Bool("all", false, "list the last operations of all the environments, including the deleted ones and the failed installs")
+	limit := flags.Int("limit", 20, "number of operations listed with --all")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var records []OperationRecord
+	if *all {
+		recent, err := a.GetRecentOperations(*limit)
+		if err != nil {
+			return err
+		}
+		records = recent
+	} else {
+		environment, err := selected.find(a)
+		if err != nil {
+			return err
+		}
+		history, err := a.GetOperationHistory(environment.ID)
+		if err != nil {
+			return err
+		}
+		records = history
+	}
+	if len(records) == 0 {
+		fmt.Fprintln(out, "No operations found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if *all {
+		fmt.Fprint(w, "ENVIRONMENT\t")
+	}
+	fmt.Fprintln(w, "ID\tOPERATION\tSTARTED\tDURATION\tSTATUS\tERROR")
+	for _, record := range records {
+		duration := "-"
+		if !record.EndedAt.IsZero() {
+			duration = record.EndedAt.Sub(record.StartedAt).Round(time.Second).String()
+		}
+		if *all {
+			// The operations on the images have no environment
+			label := record.Platform
+			if record.Name != "" {
+				label = record.Name + " " + record.Version + " (" + record.Platform + ")"
+			}
+			fmt.Fprintf(w, "%s\t", label)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			record.ID,
+			record.Kind,
+			record.StartedAt.Local().Format("2006-01-02 15:04:05"),
+			duration,
+			record.Status,
+			record.Error,
+		)
+	}
+	return w.Flush()
+}
+
+func cliLog(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("log")
+	id := flags.String("id", "", "id of the operation, as printed by the history command")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := requireCLIFlags(flags, "id"); err != nil {
+		return err
+	}
+
+	log, err := a.GetOperationLog(*id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(out, log)
+	return nil
+}
+
+func cliContexts(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("contexts")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	contexts, err := a.GetKubernetesContexts()
+	if err != nil {
+		return err
+	}
+	for _, context := range contexts {
+		fmt.Fprintln(out, context)
+	}
+	return nil
+}
+
+func cliHosts(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("hosts")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	addresses, err := a.ListHostAddresses()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDRESS\tINTERFACE\tNOTES")
+	for _, address := range addresses {
+		var notes []string
+		if address.Default {
+			notes = append(notes, "default")
+		}
+		if address.Loopback {
+			notes = append(notes, "only this machine")
+		}
+		if address.Hint != "" {
+			notes = append(notes, address.Hint)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", address.Address, address.Interface, strings.Join(notes, ", "))
+	}
+	return w.Flush()
+}
+
+func cliDiagnostics(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("diagnostics")
+	path := flags.String("path", "", "zip file to write the diagnostics to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := requireCLIFlags(flags, "path"); err != nil {
+		return err
+	}
+
+	if err := a.CreateDiagnosticsBundle(*path); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Diagnostics saved to %s\n", *path)
+	return nil
+}
+
+func cliExportImages(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("export-images")
+	platform := flags.String("platform", "docker", platformFlagUsage)
+	path := flags.String("path", "", "tar file to save the images to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := validateCLIPlatform(*platform); err != nil {
+		return err
+	}
+	if err := requireCLIFlags(flags, "path"); err != nil {
+		return err
+	}
+
+	if err := a.exportImageBundle(*platform, *path); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Images saved to %s\n", *path)
+	return nil
+}
+
+func cliLoadImages(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("load-images")
+	platform := flags.String("platform", "docker", platformFlagUsage)
+	path := flags.String("path", "", "tar file saved by export-images")
+	registry := flags.String("registry", "", "registry to push the images to (e.g. localhost:5000), required for kubernetes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := validateCLIPlatform(*platform); err != nil {
+		return err
+	}
+	if err := requireCLIFlags(flags, "path"); err != nil {
+		return err
+	}
+	if *platform == "kubernetes" && *registry == "" {
+		return errUsage{"the --registry flag is required for kubernetes"}
+	}
+
+	if err := a.LoadImageBundle(*platform, *path, *registry); err != nil {
+		return err
+	}
+	if *registry != "" {
+		fmt.Fprintf(out, "Images pushed to %s, install the environments with --var DOCKER_REGISTRY=%s\n", *registry, *registry)
+	} else {
+		fmt.Fprintf(out, "Images loaded from %s\n", *path)
+	}
+	return nil
+}
+
+func cliConnectivity(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("connectivity")
+	targetsFile := flags.String("targets", "", "json file with the targets to check instead of the default ones: [{\"name\", \"url\", \"feature\"}]")
+	reset := flags.Bool("reset", false, "check the default targets again")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *targetsFile != "" && *reset {
+		return errUsage{"only one of --targets and --reset can be given"}
+	}
+
+	if *targetsFile != "" {
+		content, err := os.ReadFile(*targetsFile)
+		if err != nil {
+			return err
+		}
+		var targets []ConnectivityTarget
+		if err := json.Unmarshal(content, &targets); err != nil {
+			return fmt.Errorf("%s: %w", *targetsFile, err)
+		}
+		if err := a.SetConnectivityTargets(targets); err != nil {
+			return err
+		}
+	} else if *reset {
+		if err := a.SetConnectivityTargets(nil); err != nil {
+			return err
+		}
+	}
+
+	result, err := a.CheckConnectivity()
+	if err != nil {
+		return err
+	}
+	if result.Offline {
+		fmt.Fprintln(out, "Offline mode is enabled, nothing was checked")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tFEATURE\tURL\tRESULT")
+	for _, probe := range result.Probes {
+		feature := probe.Feature
+		if feature == "" {
+			feature = "-"
+		}
+		outcome := probe.Error
+		if probe.Reachable {
+			outcome = fmt.Sprintf("%d in %dms", probe.StatusCode, probe.DurationMs)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", probe.Name, feature, probe.URL, outcome)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Image pulls: %s\n", reachableText(result.Images))
+	fmt.Fprintf(out, "Updates: %s\n", reachableText(result.Updates))
+	return nil
+}
+
+// How the connectivity of a feature is printed
+func reachableText(reachable bool) string {
+	if reachable {
+		return "available"
+	}
+	return "unavailable"
+}
+
+func cliNetwork(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("network")
+	proxy := flags.String("proxy", "", "proxy of the http and https requests (e.g. http://proxy.example.org:3128), empty to use the environment")
+	noProxy := flags.String("no-proxy", "", "hosts reached without the proxy, separated by commas (e.g. .example.org,10.0.0.0/8)")
+	caCertificates := flags.String("ca-certificates", "", "PEM file with certificate authorities to trust in addition to those of the system")
+	reset := flags.Bool("reset", false, "remove the network settings")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	settings, err := a.GetNetworkSettings()
+	if err != nil {
+		return err
+	}
+	// Only the given flags change the settings
+	changed := *reset
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "proxy":
+			settings.ProxyURL = *proxy
+		case "no-proxy":
+			settings.NoProxy = *noProxy
+		case "ca-certificates":
+			settings.CACertificatesPath = *caCertificates
+		}
+		changed = true
+	})
+	if *reset {
+		if flags.NFlag() > 1 {
+			return errUsage{"--reset can't be given with other flags"}
+		}
+		settings = NetworkSettings{}
+	}
+	if changed {
+		if err := a.SetNetworkSettings(settings); err != nil {
+			return err
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, setting := range []struct{ name, value string }{
+		{"Proxy", settings.ProxyURL},
+		{"No proxy", settings.NoProxy},
+		{"Certificate authorities", settings.CACertificatesPath},
+	} {
+		if setting.value == "" {
+			setting.value = "-"
+		}
+		fmt.Fprintf(w, "%s:\t%s\n", setting.name, setting.value)
+	}
+	return w.Flush()
+}
+
+func cliPorts(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("ports")
+	portRangeFlag := flags.String("range", "", "range where the free ports are looked for, e.g. 30000-31000")
+	strict := flags.Bool("strict", false, "refuse the ports outside of the range")
+	reset := flags.Bool("reset", false, "use the default range again")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	portRange, err := a.GetPortRange()
+	if err != nil {
+		return err
+	}
+	// Only the given flags change the range
+	changed := *reset
+	var parseErr error
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "range":
+			start, end, ok := strings.Cut(*portRangeFlag, "-")
+			portRange.Start, parseErr = strconv.Atoi(strings.TrimSpace(start))
+			if parseErr == nil {
+				portRange.End, parseErr = strconv.Atoi(strings.TrimSpace(end))
+			}
+			if !ok || parseErr != nil {
+				parseErr = errUsage{"the --range flag must be like 30000-31000"}
+			}
+		case "strict":
+			portRange.Strict = *strict
+		}
+		changed = true
+	})
+	if parseErr != nil {
+		return parseErr
+	}
+	if *reset {
+		if flags.NFlag() > 1 {
+			return errUsage{"--reset can't be given with other flags"}
+		}
+		portRange = PortRange{}
+	}
+	if changed {
+		if err := a.SetPortRange(portRange); err != nil {
+			return err
+		}
+		if portRange, err = a.GetPortRange(); err != nil {
+			return err
+		}
+	}
+
+	mode := "the ports outside of it can be chosen by hand"
+	if portRange.Strict {
+		mode = "strict"
+	}
+	fmt.Fprintf(out, "Port range: %d-%d (%s)\n", portRange.Start, portRange.End, mode)
+
+	reservations, err := a.store.GetPortReservations()
+	if err != nil {
+		return err
+	}
+	if len(reservations) == 0 {
+		fmt.Fprintln(out, "No ports reserved")
+		return nil
+	}
+	environments, err := a.store.GetEnvironments()
+	if err != nil {
+		return err
+	}
+	names := make(map[string]string)
+	for _, environment := range environments {
+		names[environment.ID] = environment.EnvironmentSetup.Name + " " + environment.EnvironmentSetup.Version
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PORT\tVARIABLE\tENVIRONMENT\tID")
+	for _, reservation := range reservations {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", reservation.Port, reservation.Variable, names[reservation.EnvironmentID], reservation.EnvironmentID)
+	}
+	return w.Flush()
+}
+
+func cliOffline(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("offline")
+	enable := flags.Bool("enable", false, "enable the offline mode")
+	disable := flags.Bool("disable", false, "disable the offline mode")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *enable && *disable {
+		return errUsage{"only one of --enable and --disable can be given"}
+	}
+
+	if *enable || *disable {
+		if err := a.SetOfflineMode(*enable); err != nil {
+			return err
+		}
+	}
+	offline, err := a.GetOfflineMode()
+	if err != nil {
+		return err
+	}
+	if offline {
+		fmt.Fprintln(out, "Offline mode: enabled")
+	} else {
+		fmt.Fprintln(out, "Offline mode: disabled")
+	}
+	return nil
+}
+
+func cliCheck(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("check")
+	platform := flags.String("platform", "", "only check the given platform and fail if it is not available")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *platform != "" {
+		if err := validateCLIPlatform(*platform); err != nil {
+			return err
+		}
+	}
+
+	// Check all the platforms if none was given
+	platforms := getPlatformNames()
+	if *platform != "" {
+		platforms = []string{*platform}
+	}
+
+	for _, name := range platforms {
+		if err := a.CheckPlatform(name); err != nil {
+			fmt.Fprintf(out, "%s: not available (%v)\n", name, err)
+			if *platform != "" {
+				return fmt.Errorf("%s is not available", name)
+			}
+			continue
+		}
+		fmt.Fprintf(out, "%s: available\n", name)
+	}
+	return nil
+}
+
+func cliUpdate(a *App, out io.Writer, args []string) error {
+	flags := newCLIFlagSet("update")
+	checkOnly := flags.Bool("check", false, "only check if a new version is available")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	latest := getLatestVersion()
+	if !isGreaterVersion(latest, VERSION) {
+		fmt.Fprintf(out, "Already up to date (%s)\n", VERSION)
+		return nil
+	}
+	fmt.Fprintf(out, "New version available: %s (current: %s)\n", latest, VERSION)
+	if *checkOnly {
+		return nil
+	}
+
+	if err := a.DoUpdate(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Updated to %s, restart the application to use the new version\n", latest)
+	return nil
+}
@@ -0,0 +1,819 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The operations run the executable as a worker process, which is the test binary in the tests
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && os.Args[1] == operationWorkerCommand {
+		os.Exit(runOperationWorker())
+	}
+	os.Exit(m.Run())
+}
+
+// Create a headless App on a memory store. The PATH is emptied so that no platform of the machine is used.
+func newTestCLIApp(t *testing.T) *App {
+	t.Setenv("PATH", t.TempDir())
+	return NewHeadlessApp(context.Background(), newMemoryEnvironmentStore(), io.Discard)
+}
+
+// Run a subcommand of the CLI and return what it printed
+func runTestCLI(t *testing.T, a *App, args ...string) (string, error) {
+	t.Helper()
+	for _, command := range cliCommands {
+		if command.name == args[0] {
+			var out bytes.Buffer
+			err := command.run(a, &out, args[1:])
+			return out.String(), err
+		}
+	}
+	t.Fatalf("unknown command %s", args[0])
+	return "", nil
+}
+
+func TestIsCLIInvocation(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{nil, false},
+		{[]string{"list"}, true},
+		{[]string{"install", "--name", "epos"}, true},
+		{[]string{"help"}, true},
+		{[]string{"--help"}, true},
+		{[]string{operationWorkerCommand, "id"}, true},
+		// Given by the systems to the window
+		{[]string{"-psn_0_12345"}, false},
+		{[]string{"/home/user/environment.json"}, false},
+		{[]string{"environment.json"}, false},
+		{[]string{"lst"}, false},
+	}
+	for _, tt := range tests {
+		if got := isCLIInvocation(tt.args); got != tt.want {
+			t.Errorf("isCLIInvocation(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCLIList(t *testing.T) {
+	a := newTestCLIApp(t)
+	output, err := runTestCLI(t, a, "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "No environments installed") {
+		t.Errorf("unexpected output for no environments: %q", output)
+	}
+
+	environment := Environment{ID: "env1", Platform: "docker", EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "1.0"}}
+	if err := a.store.SaveEnvironment(environment); err != nil {
+		t.Fatal(err)
+	}
+	output, err = runTestCLI(t, a, "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Docker is not found, the status of the environment can't be checked
+	for _, want := range []string{"env1", "epos", "1.0", "docker", environmentUnknown} {
+		if !strings.Contains(output, want) {
+			t.Errorf("%q is missing from the list: %q", want, output)
+		}
+	}
+}
+
+// The invalid arguments are reported as usage errors, before anything is run
+func TestCLIUsageErrors(t *testing.T) {
+	tests := [][]string{
+		{"install", "--version", "1.0"},
+		{"install", "--name", "epos", "--version", "1.0", "--platform", "vagrant"},
+		{"install", "--name", "epos", "--version", "1.0", "--platform", "kubernetes"},
+		{"install", "--name", "epos", "--version", "1.0", "--var", "UNKNOWN_VARIABLE=1"},
+		{"delete", "--name", "epos"},
+		{"populate", "--name", "epos", "--version", "1.0"},
+		{"check", "--platform", "vagrant"},
+	}
+	for _, args := range tests {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			_, err := runTestCLI(t, newTestCLIApp(t), args...)
+			if _, ok := err.(errUsage); !ok {
+				t.Errorf("expected a usage error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCLIDeleteUnknownEnvironment(t *testing.T) {
+	_, err := runTestCLI(t, newTestCLIApp(t), "delete", "--name", "epos", "--version", "1.0")
+	if !errors.Is(err, errEnvironmentNotFound) {
+		t.Errorf("expected %v, got %v", errEnvironmentNotFound, err)
+	}
+}
+
+func TestCLIHistory(t *testing.T) {
+	a := newTestCLIApp(t)
+	environment := Environment{ID: "env1", Platform: "docker", EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "1.0"}}
+	if err := a.store.SaveEnvironment(environment); err != nil {
+		t.Fatal(err)
+	}
+	output, err := runTestCLI(t, a, "history", "--id", "env1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "No operations found") {
+		t.Errorf("unexpected output for no operations: %q", output)
+	}
+
+	startedAt := time.Now()
+	record := OperationRecord{ID: "op1", Kind: "install", EnvironmentID: "env1", Platform: "docker", Name: "epos", Version: "1.0", StartedAt: startedAt, Status: operationRunning}
+	if err := a.store.AddOperation(record); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.store.AppendOperationLog("op1", "[TASK] Installing rabbitmq\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.store.FinishOperation("op1", startedAt.Add(90*time.Second), operationFailed, "pull failed"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{"history", "--id", "env1"}, {"history", "--all"}} {
+		output, err = runTestCLI(t, a, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{"op1", "install", "1m30s", operationFailed, "pull failed"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("%s: %q is missing from %q", strings.Join(args, " "), want, output)
+			}
+		}
+	}
+
+	output, err = runTestCLI(t, a, "log", "--id", "op1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "[TASK] Installing rabbitmq\n" {
+		t.Errorf("unexpected log %q", output)
+	}
+	if _, err := runTestCLI(t, a, "log"); err == nil {
+		t.Error("expected an error without --id")
+	}
+}
+
+func TestCLIExportImport(t *testing.T) {
+	a := newTestCLIApp(t)
+	variables, err := a.ReadEnvVariables("docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	environment := Environment{ID: "env1", Platform: "docker", EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "1.0"}, Variables: variables}
+	if err := a.store.SaveEnvironment(environment); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "environment.json")
+	if _, err := runTestCLI(t, a, "export", "--id", "env1", "--path", path); err != nil {
+		t.Fatal(err)
+	}
+
+	// The secrets are not in the file, they have to be given or the defaults accepted
+	_, err = runTestCLI(t, a, "import", "--path", path)
+	if _, ok := err.(errUsage); !ok || !strings.Contains(err.Error(), "POSTGRESQL_PASSWORD") {
+		t.Errorf("expected a usage error for the missing secrets, got %v", err)
+	}
+	_, err = runTestCLI(t, a, "import", "--path", path, "--var", "POSTGRESQL_PASSWORD=s3cret")
+	if _, ok := err.(errUsage); !ok || strings.Contains(err.Error(), "POSTGRESQL_PASSWORD") {
+		t.Errorf("expected a usage error for the other secrets only, got %v", err)
+	}
+
+	// The environment of the file is already installed
+	_, err = runTestCLI(t, a, "import", "--path", path, "--default-secrets")
+	if err == nil || !strings.Contains(err.Error(), "already installed") {
+		t.Errorf("expected an error for the installed environment, got %v", err)
+	}
+}
+
+func TestCLIUpgradeDryRun(t *testing.T) {
+	a := newTestCLIApp(t)
+	variables, err := a.ReadEnvVariables("docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	environment := Environment{ID: "env1", Platform: "docker", EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "1.0"}, Variables: variables}
+	if err := a.store.SaveEnvironment(environment); err != nil {
+		t.Fatal(err)
+	}
+	output, err := runTestCLI(t, a, "upgrade", "--id", "env1", "--dry-run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "up to date") {
+		t.Errorf("unexpected output for the defaults: %q", output)
+	}
+
+	// An environment installed before API_PATH was added to the defaults
+	environment.Variables = copySections(variables)
+	for _, section := range environment.Variables {
+		delete(section.Variables, "API_PATH")
+	}
+	if err := a.store.SaveEnvironment(environment); err != nil {
+		t.Fatal(err)
+	}
+	output, err = runTestCLI(t, a, "upgrade", "--id", "env1", "--dry-run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "+ API_PATH=") {
+		t.Errorf("the added variable is missing from %q", output)
+	}
+}
+
+// The environments are selected by their id, or by their name, version, platform and context
+func TestCLIEnvironmentFlags(t *testing.T) {
+	a := newTestCLIApp(t)
+	for _, environment := range []Environment{
+		{ID: "docker1", Platform: "docker", EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "1.0"}},
+		{ID: "kube1", Platform: "kubernetes", EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "1.0", Context: "cluster1"}},
+		{ID: "kube2", Platform: "kubernetes", EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "1.0", Context: "cluster2"}},
+	} {
+		if err := a.store.SaveEnvironment(environment); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--id", "kube2"}, "kube2"},
+		{[]string{"--name", "epos", "--version", "1.0"}, "docker1"},
+		{[]string{"--platform", "kubernetes", "--name", "epos", "--version", "1.0", "--context", "cluster1"}, "kube1"},
+		{[]string{"--platform", "kubernetes", "--name", "epos", "--version", "1.0", "--context", "cluster2"}, "kube2"},
+	}
+	for _, tt := range tests {
+		flags := newCLIFlagSet("test")
+		selected := addEnvironmentFlags(flags)
+		if err := flags.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		environment, err := selected.find(a)
+		if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if environment.ID != tt.want {
+			t.Errorf("%q selected %s, want %s", tt.args, environment.ID, tt.want)
+		}
+	}
+
+	// The context is needed to choose between the kubernetes environments
+	flags := newCLIFlagSet("test")
+	selected := addEnvironmentFlags(flags)
+	if err := flags.Parse([]string{"--platform", "kubernetes", "--name", "epos", "--version", "1.0"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := selected.find(a); err == nil {
+		t.Error("expected an error without --context")
+	}
+}
+
+// Put a fake executable running the script in the PATH emptied by newTestCLIApp, instead of the one of the machine
+func fakeExecutable(t *testing.T, name, script string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake executables are shell scripts")
+	}
+	if err := os.WriteFile(filepath.Join(os.Getenv("PATH"), name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCLIPrune(t *testing.T) {
+	a := newTestCLIApp(t)
+	// Only the containers of epos 1.0 are left
+	fakeExecutable(t, "docker", "printf 'epos1-0-gateway\\trunning\\n'\n")
+	for _, environment := range []Environment{
+		{ID: "env1", Platform: "docker", EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "1.0"}},
+		{ID: "env2", Platform: "docker", EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "2.0"}},
+	} {
+		if err := a.store.SaveEnvironment(environment); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output, err := runTestCLI(t, a, "prune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "Removed epos 2.0 (env2)") || strings.Contains(output, "env1") {
+		t.Errorf("unexpected output %q", output)
+	}
+	if _, err := a.store.GetEnvironment("env1"); err != nil {
+		t.Errorf("the running environment was removed: %v", err)
+	}
+
+	output, err = runTestCLI(t, a, "prune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "No missing environments") {
+		t.Errorf("unexpected output %q", output)
+	}
+}
+
+// The environments are kept when docker can't be reached
+func TestCLIPruneUnreachable(t *testing.T) {
+	a := newTestCLIApp(t)
+	fakeExecutable(t, "docker", "exit 1\n")
+	if err := a.store.SaveEnvironment(Environment{ID: "env1", Platform: "docker", EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "1.0"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runTestCLI(t, a, "prune"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.store.GetEnvironment("env1"); err != nil {
+		t.Errorf("the environment was removed: %v", err)
+	}
+}
+
+func TestCLIStatus(t *testing.T) {
+	a := newTestCLIApp(t)
+	fakeExecutable(t, "docker", `case "$1" in
+ps) printf 'epos1-0-gateway\trunning\n' ;;
+inspect) printf '%s\n' '[{"Name": "/epos1-0-gateway", "RestartCount": 2, "State": {"Status": "running", "Health": {"Status": "healthy"}}, "Config": {"Image": "epos/gateway:1.0", "Labels": {"com.docker.compose.service": "gateway"}}}]' ;;
+esac
+`)
+	dataPortal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer dataPortal.Close()
+	apiGateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer apiGateway.Close()
+
+	environment := Environment{
+		ID:               "env1",
+		Platform:         "docker",
+		EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "1.0"},
+		AccessPoints:     EposAccessPoints{DataPortal: dataPortal.URL, ApiGateway: apiGateway.URL},
+	}
+	if err := a.store.SaveEnvironment(environment); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := runTestCLI(t, a, "status", "--id", "env1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Status: running",
+		"Data Portal: " + dataPortal.URL + " (200 in",
+		"API Gateway: " + apiGateway.URL + " (503 in",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("%q is missing from %q", want, output)
+		}
+	}
+
+	// The line of the service in the table
+	want := []string{"gateway", "epos1-0-gateway", "running", "healthy", "2", "epos/gateway:1.0"}
+	found := false
+	for _, line := range strings.Split(output, "\n") {
+		if reflect.DeepEqual(strings.Fields(line), want) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("the service %q is missing from %q", want, output)
+	}
+}
+
+func TestCLILifecycle(t *testing.T) {
+	a := newTestCLIApp(t)
+	calls := filepath.Join(t.TempDir(), "calls")
+	// The containers of epos 1.0 and of epos 1.0.1, whose name starts like them
+	fakeExecutable(t, "docker", `echo "$@" >> '`+calls+`'
+case "$1" in
+ps) printf 'epos1-0-gateway\nepos1-0-rabbitmq\nepos1-0-1-gateway\n' ;;
+inspect) printf '%s\n' '[{"Name": "/epos1-0-gateway", "Config": {"Labels": {"com.docker.compose.service": "gateway"}}}, {"Name": "/epos1-0-rabbitmq", "Config": {"Labels": {"com.docker.compose.service": "rabbitmq"}}}]' ;;
+esac
+`)
+	if err := a.store.SaveEnvironment(Environment{ID: "env1", Platform: "docker", EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "1.0"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"stop", "--id", "env1"}, "stop epos1-0-gateway epos1-0-rabbitmq"},
+		{[]string{"start", "--id", "env1"}, "start epos1-0-gateway epos1-0-rabbitmq"},
+		{[]string{"restart", "--id", "env1"}, "restart epos1-0-gateway epos1-0-rabbitmq"},
+		{[]string{"restart", "--id", "env1", "--service", "gateway"}, "restart epos1-0-gateway"},
+	}
+	for _, tt := range tests {
+		os.Remove(calls)
+		if _, err := runTestCLI(t, a, tt.args...); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		content, err := os.ReadFile(calls)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		if last := lines[len(lines)-1]; last != tt.want {
+			t.Errorf("%q ran %q, want %q", tt.args, last, tt.want)
+		}
+	}
+
+	if _, err := runTestCLI(t, a, "restart", "--id", "env1", "--service", "portal"); err == nil || !strings.Contains(err.Error(), "service not found") {
+		t.Errorf("expected an error for an unknown service, got %v", err)
+	}
+
+	// The operations are in the history
+	records, err := a.store.GetOperations("env1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(tests)+1 || records[0].Kind != "restart" || records[0].Status != operationFailed {
+		t.Errorf("unexpected history %+v", records)
+	}
+}
+
+func TestCLILogs(t *testing.T) {
+	a := newTestCLIApp(t)
+	fakeExecutable(t, "docker", `case "$1" in
+ps) printf 'epos1-0-gateway\n' ;;
+inspect) printf '%s\n' '[{"Name": "/epos1-0-gateway", "Config": {"Labels": {"com.docker.compose.service": "gateway"}}}]' ;;
+logs) printf '2024-01-01T00:00:00Z connecting with the password s3cret\n' ;;
+esac
+`)
+	variables := []Section{{Name: "database", Variables: map[string]string{"POSTGRESQL_PASSWORD": "s3cret"}}}
+	if err := a.store.SaveEnvironment(Environment{ID: "env1", Platform: "docker", EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "1.0"}, Variables: variables}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{"logs", "--id", "env1"}, {"logs", "--id", "env1", "--service", "gateway", "--bundle", "logs.zip"}} {
+		if _, err := runTestCLI(t, a, args...); err == nil {
+			t.Errorf("%q: expected a usage error", args)
+		} else if _, ok := err.(errUsage); !ok {
+			t.Errorf("%q: expected a usage error, got %v", args, err)
+		}
+	}
+
+	// The secrets are masked in the logs
+	output, err := runTestCLI(t, a, "logs", "--id", "env1", "--service", "gateway", "--tail", "10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "connecting with the password") || strings.Contains(output, "s3cret") {
+		t.Errorf("unexpected logs %q", output)
+	}
+
+	path := filepath.Join(t.TempDir(), "logs.zip")
+	if _, err := runTestCLI(t, a, "logs", "--id", "env1", "--bundle", path); err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bundle.Close()
+	var names []string
+	for _, file := range bundle.File {
+		names = append(names, file.Name)
+	}
+	if want := []string{"env.env", "logs/epos1-0-gateway.log"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("the bundle has %q, want %q", names, want)
+	}
+}
+
+func TestCLIDiagnostics(t *testing.T) {
+	a := newTestCLIApp(t)
+	if _, err := runTestCLI(t, a, "diagnostics"); err == nil {
+		t.Error("expected an error without --path")
+	}
+
+	variables := []Section{{Name: "database", Variables: map[string]string{"POSTGRESQL_PASSWORD": "s3cret"}}}
+	if err := a.store.SaveEnvironment(Environment{ID: "env1", Platform: "docker", EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "1.0"}, Variables: variables}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "diagnostics.zip")
+	output, err := runTestCLI(t, a, "diagnostics", "--path", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, path) {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	bundle, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bundle.Close()
+	files := make(map[string]*zip.File)
+	for _, file := range bundle.File {
+		files[file.Name] = file
+	}
+	for _, name := range []string{"system.txt", "platforms.txt", "commands/docker-info.txt", "environments.json"} {
+		if files[name] == nil {
+			t.Errorf("%s is missing from the bundle", name)
+		}
+	}
+	if file := files["environments.json"]; file != nil {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(content), "epos") || strings.Contains(string(content), "s3cret") {
+			t.Errorf("unexpected environments %s", content)
+		}
+	}
+}
+
+func TestCLIOffline(t *testing.T) {
+	a := newTestCLIApp(t)
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"offline"}, "Offline mode: disabled"},
+		{[]string{"offline", "--enable"}, "Offline mode: enabled"},
+		{[]string{"offline"}, "Offline mode: enabled"},
+		{[]string{"offline", "--disable"}, "Offline mode: disabled"},
+	}
+	for _, tt := range tests {
+		output, err := runTestCLI(t, a, tt.args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(output) != tt.want {
+			t.Errorf("%q printed %q, want %q", tt.args, output, tt.want)
+		}
+	}
+}
+
+func TestCLIImagesUsageErrors(t *testing.T) {
+	tests := [][]string{
+		{"offline", "--enable", "--disable"},
+		{"export-images"},
+		{"export-images", "--platform", "vagrant", "--path", "images.tar"},
+		{"load-images", "--platform", "docker"},
+		{"load-images", "--platform", "kubernetes", "--path", "images.tar"},
+	}
+	for _, args := range tests {
+		_, err := runTestCLI(t, newTestCLIApp(t), args...)
+		if _, ok := err.(errUsage); !ok {
+			t.Errorf("%q: expected a usage error, got %v", args, err)
+		}
+	}
+}
+
+func TestCLIConnectivity(t *testing.T) {
+	a := newTestCLIApp(t)
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer registry.Close()
+	releases := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	releases.Close()
+
+	targets := []ConnectivityTarget{
+		{Name: "Registry", URL: registry.URL, Feature: connectivityFeatureImages},
+		{Name: "Releases", URL: releases.URL, Feature: connectivityFeatureUpdates},
+	}
+	content, err := json.Marshal(targets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "targets.json")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := runTestCLI(t, a, "connectivity", "--targets", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Registry", "401 in", "Image pulls: available", "Updates: unavailable"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("%q is missing from %q", want, output)
+		}
+	}
+
+	// Nothing is checked in offline mode, the targets are reset anyway
+	if err := a.SetOfflineMode(true); err != nil {
+		t.Fatal(err)
+	}
+	output, err = runTestCLI(t, a, "connectivity", "--reset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "nothing was checked") {
+		t.Errorf("unexpected output %q", output)
+	}
+	saved, err := a.GetConnectivityTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(saved, defaultConnectivityTargets) {
+		t.Errorf("the targets were not reset: %+v", saved)
+	}
+
+	if _, err := runTestCLI(t, a, "connectivity", "--targets", path, "--reset"); err == nil {
+		t.Error("expected a usage error with --targets and --reset")
+	}
+}
+
+func TestCLINetwork(t *testing.T) {
+	a := newTestCLIApp(t)
+	// The settings are applied to the whole process
+	t.Cleanup(func() {
+		applyNetworkSettings(NetworkSettings{})
+	})
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"network"}, []string{"Proxy:", "-"}},
+		{[]string{"network", "--proxy", "http://proxy.example.org:3128", "--no-proxy", ".example.org"}, []string{"http://proxy.example.org:3128", ".example.org"}},
+		{[]string{"network"}, []string{"http://proxy.example.org:3128", ".example.org"}},
+		{[]string{"network", "--no-proxy", "localhost"}, []string{"http://proxy.example.org:3128", "localhost"}},
+	}
+	for _, tt := range tests {
+		output, err := runTestCLI(t, a, tt.args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(output, want) {
+				t.Errorf("%q: %q is missing from %q", tt.args, want, output)
+			}
+		}
+	}
+	if settings := currentNetworkSettings(); settings.ProxyURL != "http://proxy.example.org:3128" {
+		t.Errorf("the proxy is not used: %+v", settings)
+	}
+
+	if _, err := runTestCLI(t, a, "network", "--proxy", "proxy.example.org"); err == nil {
+		t.Error("expected an error for a proxy without scheme")
+	}
+	if _, err := runTestCLI(t, a, "network", "--reset", "--proxy", "http://proxy.example.org:3128"); err == nil {
+		t.Error("expected a usage error with --reset and --proxy")
+	}
+	if _, err := runTestCLI(t, a, "network", "--reset"); err != nil {
+		t.Fatal(err)
+	}
+	if settings, err := a.GetNetworkSettings(); err != nil || settings != (NetworkSettings{}) {
+		t.Errorf("the settings were not reset: %+v %v", settings, err)
+	}
+}
+
+func TestCLIHosts(t *testing.T) {
+	a := newTestCLIApp(t)
+	output, err := runTestCLI(t, a, "hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "127.0.0.1 ") && strings.Contains(line, "only this machine") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("the loopback address is missing from %q", output)
+	}
+
+	// The host is checked before anything is installed
+	for _, host := range []string{"203.0.113.7", "example.org"} {
+		_, err := runTestCLI(t, a, "install", "--name", "epos", "--version", "1.0", "--host", host)
+		if err == nil || !strings.Contains(err.Error(), host) {
+			t.Errorf("expected an error for the host %s, got %v", host, err)
+		}
+	}
+	_, err = runTestCLI(t, a, "install", "--platform", "kubernetes", "--context", "cluster", "--name", "epos", "--version", "1.0", "--host", "127.0.0.1")
+	if err == nil {
+		t.Error("expected an error for a host on kubernetes")
+	}
+}
+
+func TestCLIPorts(t *testing.T) {
+	a := newTestCLIApp(t)
+	if err := a.store.SaveEnvironment(Environment{ID: "env1", Platform: "docker", EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "1.0"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"ports"}, []string{"Port range: 30000-39999 (the ports outside of it can be chosen by hand)", "No ports reserved"}},
+		{[]string{"ports", "--range", "31000-31100", "--strict"}, []string{"Port range: 31000-31100 (strict)"}},
+		{[]string{"ports", "--strict=false"}, []string{"Port range: 31000-31100 (the ports outside"}},
+		{[]string{"ports", "--reset"}, []string{"Port range: 30000-39999"}},
+	}
+	for _, tt := range tests {
+		output, err := runTestCLI(t, a, tt.args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(output, want) {
+				t.Errorf("%q: %q is missing from %q", tt.args, want, output)
+			}
+		}
+	}
+
+	if err := a.store.ReservePorts("env1", []PortReservation{{Port: 32000, Variable: "API_PORT"}}); err != nil {
+		t.Fatal(err)
+	}
+	output, err := runTestCLI(t, a, "ports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"32000", "API_PORT", "epos", "1.0", "env1"}
+	found := false
+	for _, line := range strings.Split(output, "\n") {
+		if reflect.DeepEqual(strings.Fields(line), want) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("the reservation %q is missing from %q", want, output)
+	}
+
+	for _, args := range [][]string{{"ports", "--range", "31000"}, {"ports", "--range", "a-b"}, {"ports", "--reset", "--strict"}} {
+		if _, err := runTestCLI(t, a, args...); err == nil {
+			t.Errorf("%q: expected an error", args)
+		}
+	}
+	if _, err := runTestCLI(t, a, "ports", "--range", "31100-31000"); err == nil {
+		t.Error("expected an error for a range that ends before it starts")
+	}
+}
+
+func TestCLIContextsAndCheck(t *testing.T) {
+	// The checks add /usr/local/bin to the PATH, its executables would be used instead of the fake ones
+	for _, name := range []string{"docker", "kubectl", "podman"} {
+		if _, err := os.Stat(filepath.Join("/usr/local/bin", name)); err == nil {
+			t.Skipf("%s is installed in /usr/local/bin", name)
+		}
+	}
+	a := newTestCLIApp(t)
+	if _, err := runTestCLI(t, a, "contexts"); err == nil {
+		t.Error("expected an error without kubectl")
+	}
+
+	fakeExecutable(t, "kubectl", `case "$1 $2" in
+"config get-contexts") printf 'cluster1\ncluster2\n' ;;
+"version --client") ;;
+*) exit 1 ;;
+esac
+`)
+	output, err := runTestCLI(t, a, "contexts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "cluster1\ncluster2\n" {
+		t.Errorf("unexpected contexts %q", output)
+	}
+
+	// Docker and kubectl are there, podman is not
+	fakeExecutable(t, "docker", "exit 0\n")
+	output, err = runTestCLI(t, a, "check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"docker: available", "kubernetes: available", "podman: not available (podman is not installed)"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("%q is missing from %q", want, output)
+		}
+	}
+	if _, err := runTestCLI(t, a, "check", "--platform", "podman"); err == nil {
+		t.Error("expected an error for podman")
+	}
+	if _, err := runTestCLI(t, a, "check", "--platform", "docker"); err != nil {
+		t.Errorf("docker: %v", err)
+	}
+}
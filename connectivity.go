@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The setting with the targets checked by CheckConnectivity as json, the defaults if empty
+const connectivityTargetsSetting = "connectivityTargets"
+
+// The features of the app that need the network, the targets they need are given by ConnectivityTarget.Feature
+const (
+	// The installs pull the images from the registries and look up their tags on Docker Hub
+	connectivityFeatureImages = "images"
+	// The check and the download of the new versions of the app on GitHub
+	connectivityFeatureUpdates = "updates"
+)
+
+// ConnectivityTarget is an endpoint needed by a feature of the app
+type ConnectivityTarget struct {
+	Name string `json:"name"`
+	// An http or https url, any answer (even an error status) means that it can be reached
+	URL string `json:"url"`
+	// images or updates, empty if no feature depends on it
+	Feature string `json:"feature"`
+}
+
+// ConnectivityProbe is the result of the request to a target
+type ConnectivityProbe struct {
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	Feature    string `json:"feature"`
+	Reachable  bool   `json:"reachable"`
+	StatusCode int    `json:"statusCode"`
+	DurationMs int64  `json:"durationMs"`
+	// Why the request failed, empty if the target answered
+	Error string `json:"error"`
+}
+
+// ConnectivityResult tells which features of the app can reach what they need
+type ConnectivityResult struct {
+	// The offline mode is enabled: nothing was checked and the features needing the network are disabled
+	Offline bool                `json:"offline"`
+	Probes  []ConnectivityProbe `json:"probes"`
+	// Every target of the feature answered
+	Images    bool      `json:"images"`
+	Updates   bool      `json:"updates"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// The targets checked when none were configured. The registries set as DOCKER_REGISTRY of the environments are added to them.
+var defaultConnectivityTargets = []ConnectivityTarget{
+	{Name: "Docker Hub registry", URL: "https://registry-1.docker.io/v2/", Feature: connectivityFeatureImages},
+	{Name: "Docker Hub tags", URL: "https://hub.docker.com/v2/repositories/epos/", Feature: connectivityFeatureImages},
+	{Name: "GitHub releases", URL: "https://api.github.com/repos/epos-eu/opensource-desktop/releases/latest", Feature: connectivityFeatureUpdates},
+}
+
+// Check that the endpoints needed by the features of the app can be reached. The requests use the proxy and the
+// certificates of the network settings like the other requests of the app.
+func (a *App) CheckConnectivity() (ConnectivityResult, error) {
+	result := ConnectivityResult{Probes: []ConnectivityProbe{}, CheckedAt: time.Now()}
+	if a.isOfflineMode() {
+		result.Offline = true
+		return result, nil
+	}
+
+	targets, err := a.GetConnectivityTargets()
+	if err != nil {
+		return result, err
+	}
+	targets = append(targets, a.registryConnectivityTargets(targets)...)
+
+	result.Probes = make([]ConnectivityProbe, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result.Probes[i] = probeConnectivityTarget(a.ctx, target)
+		}()
+	}
+	wg.Wait()
+
+	result.Images = featureReachable(result.Probes, connectivityFeatureImages)
+	result.Updates = featureReachable(result.Probes, connectivityFeatureUpdates)
+	return result, nil
+}
+
+// Get the targets checked by CheckConnectivity
+func (a *App) GetConnectivityTargets() ([]ConnectivityTarget, error) {
+	value, err := a.store.GetSetting(connectivityTargetsSetting)
+	if err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return append([]ConnectivityTarget{}, defaultConnectivityTargets...), nil
+	}
+
+	var targets []ConnectivityTarget
+	if err := json.Unmarshal([]byte(value), &targets); err != nil {
+		return nil, fmt.Errorf("error reading the connectivity targets: %w", err)
+	}
+	return targets, nil
+}
+
+// Replace the targets checked by CheckConnectivity, the default ones are used again if there are none
+func (a *App) SetConnectivityTargets(targets []ConnectivityTarget) error {
+	if len(targets) == 0 {
+		return a.store.SetSetting(connectivityTargetsSetting, "")
+	}
+	for _, target := range targets {
+		if err := target.validate(); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(targets)
+	if err != nil {
+		return err
+	}
+	return a.store.SetSetting(connectivityTargetsSetting, string(data))
+}
+
+func (t ConnectivityTarget) validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("the connectivity target %s has no name", t.URL)
+	}
+	parsed, err := url.Parse(t.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("the url of the connectivity target %s must be an http or https url: %s", t.Name, t.URL)
+	}
+	switch t.Feature {
+	case "", connectivityFeatureImages, connectivityFeatureUpdates:
+		return nil
+	}
+	return fmt.Errorf("unknown feature of the connectivity target %s: %s", t.Name, t.Feature)
+}
+
+// The registries in the DOCKER_REGISTRY variable of the installed environments that are not already in targets
+func (a *App) registryConnectivityTargets(targets []ConnectivityTarget) []ConnectivityTarget {
+	environments, err := a.store.GetEnvironments()
+	if err != nil {
+		return nil
+	}
+
+	checked := map[string]bool{}
+	for _, target := range targets {
+		if parsed, err := url.Parse(target.URL); err == nil {
+			checked[parsed.Host] = true
+		}
+	}
+	var registries []ConnectivityTarget
+	for _, environment := range environments {
+		for _, section := range environment.Variables {
+			host := registryHost(section.Variables["DOCKER_REGISTRY"])
+			if host == "" || checked[host] {
+				continue
+			}
+			checked[host] = true
+			registries = append(registries, ConnectivityTarget{
+				Name:    "Registry " + host,
+				URL:     "https://" + host + "/v2/",
+				Feature: connectivityFeatureImages,
+			})
+		}
+	}
+	return registries
+}
+
+// The host of the registry in a DOCKER_REGISTRY value, empty if it is a namespace of Docker Hub (e.g. epos).
+// Like docker, the first part of the value is a host if it has a dot or a port, or is localhost.
+func registryHost(registry string) string {
+	host, _, _ := strings.Cut(registry, "/")
+	if strings.ContainsAny(host, ".:") || host == "localhost" {
+		return host
+	}
+	return ""
+}
+
+// Send a request to the target, it can be reached if it answers anything
+func probeConnectivityTarget(ctx context.Context, target ConnectivityTarget) ConnectivityProbe {
+	probe := probeEndpoint(ctx, networkHTTPClient(), target.URL)
+	return ConnectivityProbe{
+		Name:       target.Name,
+		URL:        target.URL,
+		Feature:    target.Feature,
+		Reachable:  probe.Error == "",
+		StatusCode: probe.StatusCode,
+		DurationMs: probe.DurationMs,
+		Error:      probe.Error,
+	}
+}
+
+// If all the targets of the feature were reached
+func featureReachable(probes []ConnectivityProbe, feature string) bool {
+	for _, probe := range probes {
+		if probe.Feature == feature && !probe.Reachable {
+			return false
+		}
+	}
+	return true
+}
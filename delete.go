@@ -1,77 +1,45 @@
 package main
 
 import (
-	"database/sql"
-	"fmt"
-
-	dockerMethods "github.com/epos-eu/opensource-docker/cmd/methods"
-	kubernetesMethods "github.com/epos-eu/opensource-kubernetes/cmd/methods"
+	"os"
 )
 
-// Deletes an installed environment from the database given its name and version
-func (a *App) DeleteInstalledEnvironment(platform, name, version, context string) error {
-
-	fmt.Println("Platform: ", platform)
-	fmt.Println("Name: ", name)
-	fmt.Println("Version: ", version)
-	fmt.Println("Context: ", context)
-
-	var err error
-
-	if platform == "docker" {
-		err = deleteDockerEnvironment(name, version)
-	} else if platform == "kubernetes" {
-		err = deleteKubernetesEnvironment(name, context)
-	} else {
-		return fmt.Errorf("unknown platform: %s", platform)
-	}
+// Deletes an installed environment from the system and the database given its id
+func (a *App) DeleteInstalledEnvironment(id string) error {
+	environment, err := a.store.GetEnvironment(id)
 	if err != nil {
 		return err
 	}
 
-	// If the environment was successfully deleted, delete it from the database
-	err = deleteEnvironmentFromDatabase(name, version, platform, context)
-
-	return err
-}
-
-// Deletes an installed environment from the database given its name and version
-func deleteEnvironmentFromDatabase(name, version, platform, context string) error {
-	// Open the database
-	db, err := sql.Open("sqlite3", databasePath)
+	driver, err := getPlatformDriver(environment.Platform)
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
-	// Delete the environment from the database
-	result, err := db.Exec("DELETE FROM environments WHERE name = ? AND version = ? AND platform = ? AND context = ?", name, version, platform, context)
-	// If there was an error or the query didn't match any rows, return the error
-	if err != nil || result == nil {
+	// Get the environment variables as a temp file
+	envFilePath, err := generateTempFile(os.TempDir(), "env", variablesToBinary(environment.Variables))
+	if err != nil {
 		return err
 	}
 
-	return nil
-}
+	// Call the delete cmd, sending its output to the frontend. It can be cancelled with CancelOperation
+	ctx, op := a.startOperation("delete", environment)
+	defer a.endOperation(op)
+	_, err = a.runOperation(ctx, op, driver, workerRequest{
+		Operation:   "delete",
+		EnvFilePath: envFilePath,
+		Environment: environment,
+	})
 
-func deleteDockerEnvironment(name, version string) error {
-	// Get the environment variables as a temp file
-	envFilePath, err := getEnvironmentVariablesTempFilePath(name, version, "docker")
+	//Remove the temporary file even if there was an error
+	os.Remove(envFilePath)
 
-	// Call the delete cmd
-	err = dockerMethods.DeleteEnvironment(
-		envFilePath, // environment variables file path
-		"",          // docker compose file path
-		name,        // environment name
-		version,     // environment version
-	)
-	return err
-}
+	if err != nil {
+		return err
+	}
 
-func deleteKubernetesEnvironment(name, context string) error {
-	// Call the delete cmd
-	return kubernetesMethods.DeleteEnvironment(
-		context, // kubernetes context
-		name,    // namespace
-	)
+	// If the environment was successfully deleted, delete it from the store
+	err = a.store.DeleteEnvironment(id)
+
+	return err
 }
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// The time given to each command run to collect the diagnostics, a cluster that can't be reached must not block the bundle
+const diagnosticsCommandTimeout = 20 * time.Second
+
+// The number of operations whose output is added to the diagnostics, the most recent ones of all the environments
+const diagnosticsOperations = 30
+
+// The commands whose output is added to the diagnostics, by file name.
+// Only commands that print no credentials: e.g. kubectl config view is left out.
+var diagnosticsCommands = []struct {
+	file string
+	args []string
+}{
+	{"docker-version.txt", []string{"docker", "version"}},
+	{"docker-info.txt", []string{"docker", "info"}},
+	{"docker-compose-version.txt", []string{"docker", "compose", "version"}},
+	{"docker-ps.txt", []string{"docker", "ps", "-a"}},
+	{"podman-version.txt", []string{"podman", "version"}},
+	{"podman-info.txt", []string{"podman", "info"}},
+	{"podman-ps.txt", []string{"podman", "ps", "-a"}},
+	{"kubectl-version.txt", []string{"kubectl", "version", "--client"}},
+	{"kubectl-contexts.txt", []string{"kubectl", "config", "get-contexts"}},
+}
+
+// Write a zip file at path with what is needed to look into a problem: the version of the app and the system,
+// the checks of the platforms, the kubernetes contexts, the installed environments with their secrets masked
+// and the output of the last operations, including those of the deleted environments and of the failed installs
+func (a *App) CreateDiagnosticsBundle(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	bundle := zip.NewWriter(file)
+	if err := a.addDiagnosticsToZip(bundle); err != nil {
+		bundle.Close()
+		return err
+	}
+	if err := bundle.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Ask where to save the diagnostics bundle, returns an empty path if the dialog was cancelled
+func (a *App) SaveDiagnosticsBundleDialog() (string, error) {
+	return wailsRuntime.SaveFileDialog(a.ctx, wailsRuntime.SaveDialogOptions{
+		Title:           "Save the diagnostics",
+		DefaultFilename: "epos-diagnostics-" + time.Now().Format("20060102-150405") + ".zip",
+		Filters:         []wailsRuntime.FileFilter{{DisplayName: "Zip file (*.zip)", Pattern: "*.zip"}},
+	})
+}
+
+func (a *App) addDiagnosticsToZip(bundle *zip.Writer) error {
+	if err := addZipFile(bundle, "system.txt", []byte(systemDiagnostics())); err != nil {
+		return err
+	}
+
+	// The checks also add the folders of the platforms to the PATH, before the commands are run
+	var checks strings.Builder
+	for _, platform := range getPlatformNames() {
+		if err := a.CheckPlatform(platform); err != nil {
+			fmt.Fprintf(&checks, "%s: not available (%v)\n", platform, err)
+		} else {
+			fmt.Fprintf(&checks, "%s: available\n", platform)
+		}
+	}
+	if err := addZipFile(bundle, "platforms.txt", []byte(checks.String())); err != nil {
+		return err
+	}
+
+	for _, command := range diagnosticsCommands {
+		if err := addZipFile(bundle, "commands/"+command.file, runDiagnosticsCommand(a.ctx, command.args)); err != nil {
+			return err
+		}
+	}
+
+	contexts, err := a.GetKubernetesContexts()
+	text := strings.Join(contexts, "\n") + "\n"
+	if err != nil {
+		text = fmt.Sprintf("Error listing the contexts: %v\n", err)
+	}
+	if err := addZipFile(bundle, "kubernetes-contexts.txt", []byte(text)); err != nil {
+		return err
+	}
+
+	return a.addEnvironmentDiagnostics(bundle)
+}
+
+// Add the installed environments, with their status and their secrets masked, and the output of the last operations
+// of all the environments
+func (a *App) addEnvironmentDiagnostics(bundle *zip.Writer) error {
+	environments, err := a.store.GetEnvironments()
+	if err != nil {
+		if err := addZipFile(bundle, "environments-error.txt", []byte(err.Error()+"\n")); err != nil {
+			return err
+		}
+	}
+	reconcileEnvironments(environments)
+
+	masked := []Environment{}
+	var secrets []string
+	for _, environment := range environments {
+		secrets = append(secrets, secretValuesOf(environment.Variables)...)
+		environment.Variables = maskSecrets(environment.Variables)
+		masked = append(masked, environment)
+	}
+	if err := addZipJSON(bundle, "environments.json", masked); err != nil {
+		return err
+	}
+
+	// The operations of the deleted environments and of the failed installs are the ones most needed by a support request
+	records, err := a.store.GetRecentOperations(diagnosticsOperations)
+	if err != nil {
+		return addZipFile(bundle, "operations-error.txt", []byte(err.Error()+"\n"))
+	}
+	if err := addZipJSON(bundle, "operations/history.json", records); err != nil {
+		return err
+	}
+	for _, record := range records {
+		log, err := a.store.GetOperationLog(record.ID)
+		if err != nil {
+			log = fmt.Sprintf("Error reading the log: %v\n", err)
+		}
+		// The logs saved before the secrets were masked in the output
+		log = redactSecrets(log, secrets)
+		if err := addZipFile(bundle, "operations/"+record.ID+"-"+record.Kind+".log", []byte(log)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add a file with the value as indented json to a zip
+func addZipJSON(bundle *zip.Writer, name string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return addZipFile(bundle, name, data)
+}
+
+// Run a command and return its output, followed by its error if it failed
+func runDiagnosticsCommand(ctx context.Context, args []string) []byte {
+	ctx, cancel := context.WithTimeout(ctx, diagnosticsCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	hideConsoleWindow(cmd)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		output = append(output, fmt.Sprintf("\n%s: %v\n", strings.Join(args, " "), err)...)
+	}
+	return output
+}
+
+// The version of the app, of Go and of the operating system
+func systemDiagnostics() string {
+	var info strings.Builder
+	fmt.Fprintf(&info, "App version: %s\n", VERSION)
+	fmt.Fprintf(&info, "Created at: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&info, "OS: %s\n", runtime.GOOS)
+	fmt.Fprintf(&info, "Architecture: %s\n", runtime.GOARCH)
+	fmt.Fprintf(&info, "CPUs: %d\n", runtime.NumCPU())
+	fmt.Fprintf(&info, "Go version: %s\n", runtime.Version())
+	fmt.Fprintf(&info, "PATH: %s\n", os.Getenv("PATH"))
+
+	// The release of the operating system
+	var release []string
+	switch runtime.GOOS {
+	case "windows":
+		release = []string{"cmd", "/c", "ver"}
+	case "darwin":
+		release = []string{"sw_vers"}
+	default:
+		release = []string{"uname", "-a"}
+	}
+	fmt.Fprintf(&info, "\n%s", runDiagnosticsCommand(context.Background(), release))
+	if data, err := os.ReadFile("/etc/os-release"); err == nil {
+		fmt.Fprintf(&info, "\n%s", data)
+	}
+	return info.String()
+}
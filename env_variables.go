@@ -0,0 +1,183 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// The line above and below the name of each section of the env.env files
+const envSectionDelimiter = "# ************************************************************************************************************"
+
+// VariableDetails describes a variable of the env.env file of a platform
+type VariableDetails struct {
+	Name string `json:"name"`
+	// The comment written before the variable, or after its value on the same line
+	Description string `json:"description"`
+	// The value in the env.env file
+	Default string `json:"default"`
+}
+
+// Parse the env.env file and return the sections with their variables in the order of the file.
+// The comments before the first variable of a section, separated from it by an empty line, describe the section,
+// the comments just before a variable describe the variable.
+func readEnvFile(file []byte) ([]Section, error) {
+	var sections []Section
+	// The comments read since the last variable or empty line
+	var comments []string
+
+	lines := strings.Split(strings.ReplaceAll(string(file), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+
+		// Check if the line is a section header:
+		// 		# ************************************************************************************************************
+		// 		#                                       SOME TEXT
+		// 		# ************************************************************************************************************
+		if line == envSectionDelimiter && i+2 < len(lines) && strings.HasPrefix(lines[i+1], "#") && strings.TrimSpace(lines[i+2]) == envSectionDelimiter {
+			sections = append(sections, Section{
+				// Remove the leading and trailing spaces and the # character
+				Name:      strings.TrimSpace(lines[i+1])[1:],
+				Variables: make(map[string]string),
+				Details:   []VariableDetails{},
+			})
+			comments = nil
+			i += 2
+			continue
+		}
+
+		switch {
+		case line == "":
+			// A comment block at the start of a section, not attached to a variable, is its description
+			if len(comments) > 0 && len(sections) > 0 {
+				section := &sections[len(sections)-1]
+				if len(section.Details) == 0 && section.Description == "" {
+					section.Description = strings.Join(comments, "\n")
+				}
+			}
+			comments = nil
+		case strings.HasPrefix(line, "#"):
+			comments = append(comments, strings.TrimSpace(strings.TrimPrefix(line, "#")))
+		case strings.Contains(line, "="):
+			// Parse the variable and its default value
+			parts := strings.SplitN(line, "=", 2)
+			key := strings.TrimSpace(parts[0])
+			value, comment := parseEnvValue(parts[1])
+
+			description := strings.Join(comments, "\n")
+			if comment != "" {
+				description = strings.TrimSpace(description + "\n" + comment)
+			}
+			comments = nil
+
+			// Add the variable to the current section, the variables before the first section are ignored
+			if len(sections) == 0 {
+				continue
+			}
+			section := &sections[len(sections)-1]
+			if _, ok := section.Variables[key]; ok {
+				// The last value is the one used, keep the position of the first
+				section.Variables[key] = value
+				for j := range section.Details {
+					if section.Details[j].Name == key {
+						section.Details[j].Default = value
+					}
+				}
+				continue
+			}
+			section.Variables[key] = value
+			section.Details = append(section.Details, VariableDetails{Name: key, Description: description, Default: value})
+		}
+	}
+
+	return sections, nil
+}
+
+// Split the part of a line after the = in the value, without the quotes, and the comment after it
+func parseEnvValue(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "\"") {
+		if end := strings.Index(text[1:], "\""); end >= 0 {
+			value := text[1 : end+1]
+			rest := strings.TrimSpace(text[end+2:])
+			return value, strings.TrimSpace(strings.TrimPrefix(rest, "#"))
+		}
+	}
+	// Like in docker compose, a # preceded by a space starts a comment in an unquoted value
+	if i := strings.Index(text, " #"); i >= 0 {
+		return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+2:])
+	}
+	// Remove the quotes from the value
+	return strings.Trim(text, "\""), ""
+}
+
+// Convert the variables to the content of an env file, in the format of the env.env files.
+// The sections and the variables keep their order, the variables without details are written after the others
+// sorted by name, so that the same variables always give the same file.
+func variablesToBinary(variables []Section) []byte {
+	var result []string
+	for _, section := range variables {
+		result = append(result, envSectionDelimiter, "#"+section.Name, envSectionDelimiter, "")
+		if section.Description != "" {
+			result = append(result, envComment(section.Description)...)
+			result = append(result, "")
+		}
+
+		for _, name := range orderedVariableNames(section) {
+			for _, details := range section.Details {
+				if details.Name == name && details.Description != "" {
+					result = append(result, envComment(details.Description)...)
+				}
+			}
+			result = append(result, name+"="+section.Variables[name])
+		}
+		result = append(result, "")
+	}
+
+	return []byte(strings.Join(result, "\n"))
+}
+
+// The names of the variables of the section, in the order of its details then sorted by name
+func orderedVariableNames(section Section) []string {
+	var names []string
+	listed := make(map[string]bool)
+	for _, details := range section.Details {
+		if _, ok := section.Variables[details.Name]; ok && !listed[details.Name] {
+			names = append(names, details.Name)
+			listed[details.Name] = true
+		}
+	}
+
+	var others []string
+	for name := range section.Variables {
+		if !listed[name] {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	return append(names, others...)
+}
+
+// The lines of a comment for the env file. The = are left out, the kubernetes installer reads every line with one as a variable.
+func envComment(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, strings.TrimSpace("# "+strings.ReplaceAll(line, "=", " ")))
+	}
+	return lines
+}
+
+// Add the descriptions and the order of the variables of the platform to sections that don't have them,
+// like the ones of the environments installed by the previous versions of the app
+func addVariableDetails(sections []Section, defaults []Section) {
+	for i := range sections {
+		if len(sections[i].Details) > 0 {
+			continue
+		}
+		for _, defaultSection := range defaults {
+			if defaultSection.Name == sections[i].Name {
+				sections[i].Description = defaultSection.Description
+				sections[i].Details = append([]VariableDetails(nil), defaultSection.Details...)
+			}
+		}
+	}
+}
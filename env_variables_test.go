@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// The variables written by variablesToBinary are read back the same by readEnvFile
+func TestVariablesToBinaryRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		variables []Section
+	}{
+		{
+			name: "sections with details",
+			variables: []Section{
+				{
+					Name:        "Ports",
+					Description: "The ports of the services",
+					Variables:   map[string]string{"API_PORT": "33000", "DATA_PORTAL_PORT": "32000"},
+					Details: []VariableDetails{
+						{Name: "DATA_PORTAL_PORT", Description: "The port of the data portal", Default: "32000"},
+						{Name: "API_PORT", Description: "The port of the gateway\non two lines", Default: "33000"},
+					},
+				},
+				{
+					Name:      "Images",
+					Variables: map[string]string{"GATEWAY_IMAGE": "epos/gateway:latest"},
+					Details:   []VariableDetails{{Name: "GATEWAY_IMAGE", Default: "epos/gateway:latest"}},
+				},
+			},
+		},
+		{
+			name: "empty value",
+			variables: []Section{
+				{
+					Name:      "Security",
+					Variables: map[string]string{"SECURITY_KEY": ""},
+					Details:   []VariableDetails{{Name: "SECURITY_KEY"}},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			read, err := readEnvFile(variablesToBinary(test.variables))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(read, test.variables) {
+				t.Errorf("read back\n%+v\nwant\n%+v", read, test.variables)
+			}
+		})
+	}
+}
+
+// The env.env files of the platforms give the same variables once written and read back
+func TestVariablesToBinaryRoundTripDefaults(t *testing.T) {
+	for _, platform := range []string{"docker", "kubernetes", "podman"} {
+		t.Run(platform, func(t *testing.T) {
+			driver, err := getPlatformDriver(platform)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defaults, err := driver.DefaultVariables()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(defaults) == 0 {
+				t.Fatal("no default variables")
+			}
+			read, err := readEnvFile(variablesToBinary(defaults))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(read, defaults) {
+				t.Errorf("the defaults of %s changed once written and read back", platform)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Written in every environment file so that other json files are not imported by mistake
+const environmentFileKind = "epos-environment"
+
+// The version of the format of the environment files, to be increased when the format changes.
+// Files with a newer version are refused, the older ones must keep being read.
+const environmentFileFormatVersion = 1
+
+// The definition of an environment as exported to a file, to install the same environment on another machine.
+// The access points are not exported as they depend on the machine, the secrets only if asked.
+type environmentFile struct {
+	Kind             string           `json:"kind"`
+	FormatVersion    int              `json:"formatVersion"`
+	AppVersion       string           `json:"appVersion"`
+	ExportedAt       time.Time        `json:"exportedAt"`
+	Platform         string           `json:"platform"`
+	EnvironmentSetup EnvironmentSetup `json:"environmentSetup"`
+	Variables        []Section        `json:"variables"`
+}
+
+// Ask where to save the definition of an installed environment and write it there, without the secrets.
+// Returns the path of the file, empty if the dialog was cancelled.
+func (a *App) ExportEnvironment(id string) (string, error) {
+	environment, err := a.store.GetEnvironment(id)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := wailsRuntime.SaveFileDialog(a.ctx, wailsRuntime.SaveDialogOptions{
+		Title:           "Export the environment",
+		DefaultFilename: environment.EnvironmentSetup.Name + "-" + environment.EnvironmentSetup.Version + ".json",
+		Filters:         environmentFileFilters(),
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+
+	return path, writeEnvironmentFile(path, environment, false)
+}
+
+// Install the environment defined in the file at path, exported by ExportEnvironment. The values of the secrets
+// missing from the file (see GetEnvironmentFileSecrets) are given in secrets. Returns the id of the new environment.
+func (a *App) ImportEnvironment(path string, secrets map[string]string) (string, error) {
+	environment, missing, err := a.readEnvironmentFile(path)
+	if err != nil {
+		return "", err
+	}
+	if err := setMissingSecrets(environment.Variables, missing, secrets); err != nil {
+		return "", err
+	}
+
+	setup := environment.EnvironmentSetup
+	if a.IsEnvironmentInstalled(setup.Name, setup.Version, environment.Platform, setup.Context) {
+		return "", fmt.Errorf("environment %s %s is already installed", setup.Name, setup.Version)
+	}
+
+	return a.InstallEnvironment(environment.Platform, setup, environment.Variables, false, false)
+}
+
+// Get the secrets that the environment file at path has no value for, they have to be given to ImportEnvironment
+func (a *App) GetEnvironmentFileSecrets(path string) ([]string, error) {
+	_, missing, err := a.readEnvironmentFile(path)
+	return missing, err
+}
+
+// Ask for an environment file to import, returns an empty path if the dialog was cancelled
+func (a *App) OpenEnvironmentFileDialog() (string, error) {
+	return wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title:   "Import an environment",
+		Filters: environmentFileFilters(),
+	})
+}
+
+func environmentFileFilters() []wailsRuntime.FileFilter {
+	return []wailsRuntime.FileFilter{{DisplayName: "EPOS environment (*.json)", Pattern: "*.json"}}
+}
+
+// Write the definition of the environment to the file at path. Without the secrets,
+// they have to be given again on import.
+func writeEnvironmentFile(path string, environment Environment, includeSecrets bool) error {
+	// Only the values are exported, the descriptions come from the env.env file of the platform on import
+	var variables []Section
+	for _, section := range copySections(environment.Variables) {
+		if !includeSecrets {
+			for name := range section.Variables {
+				if isSecretVariable(name) {
+					delete(section.Variables, name)
+				}
+			}
+		}
+		variables = append(variables, Section{Name: section.Name, Variables: section.Variables})
+	}
+
+	file := environmentFile{
+		Kind:             environmentFileKind,
+		FormatVersion:    environmentFileFormatVersion,
+		AppVersion:       VERSION,
+		ExportedAt:       time.Now().UTC(),
+		Platform:         environment.Platform,
+		EnvironmentSetup: environment.EnvironmentSetup,
+		Variables:        variables,
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// Read an environment file and check it can be installed by this version of the app. The secrets missing from the
+// file are returned too, they have the default values of the platform.
+func (a *App) readEnvironmentFile(path string) (Environment, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Environment{}, nil, err
+	}
+
+	var file environmentFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Environment{}, nil, fmt.Errorf("invalid environment file %s: %w", path, err)
+	}
+	if file.Kind != environmentFileKind {
+		return Environment{}, nil, fmt.Errorf("%s is not an environment file", path)
+	}
+	if file.FormatVersion < 1 || file.FormatVersion > environmentFileFormatVersion {
+		return Environment{}, nil, fmt.Errorf("the environment file %s was created by a newer version of the application (%s), please update the application", path, file.AppVersion)
+	}
+
+	if file.EnvironmentSetup.Name == "" || file.EnvironmentSetup.Version == "" {
+		return Environment{}, nil, fmt.Errorf("the environment file %s has no name or version", path)
+	}
+	if file.Platform == "kubernetes" && file.EnvironmentSetup.Context == "" {
+		return Environment{}, nil, fmt.Errorf("the environment file %s has no kubernetes context", path)
+	}
+
+	defaults, err := a.ReadEnvVariables(file.Platform)
+	if err != nil {
+		return Environment{}, nil, err
+	}
+	missing := missingSecrets(defaults, file.Variables)
+	variables, err := mergeImportedVariables(defaults, file.Variables)
+	if err != nil {
+		return Environment{}, nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// The host can be an address of the machine that exported the environment, the default route is used instead
+	setup := file.EnvironmentSetup
+	if checkEnvironmentHost(file.Platform, setup.Host) != nil {
+		setup.Host = ""
+	}
+
+	return Environment{
+		Platform:         file.Platform,
+		EnvironmentSetup: setup,
+		Variables:        variables,
+	}, missing, nil
+}
+
+// Set the values of the imported variables in the default sections of the platform.
+// The variables missing from the file keep their default value, the ones unknown to the platform are an error.
+func mergeImportedVariables(defaults []Section, imported []Section) ([]Section, error) {
+	var unknown []string
+	for _, section := range imported {
+		for key, value := range section.Variables {
+			found := false
+			for _, defaultSection := range defaults {
+				if _, ok := defaultSection.Variables[key]; ok {
+					defaultSection.Variables[key] = value
+					found = true
+				}
+			}
+			if !found {
+				unknown = append(unknown, key)
+			}
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown variables: %s", strings.Join(unknown, ", "))
+	}
+	return defaults, nil
+}
+
+// Get the secrets of the platform that have no value in the imported sections, sorted. They are not installed with
+// their default values, the ones of the env.env file are known by anyone.
+func missingSecrets(defaults []Section, imported []Section) []string {
+	values := make(map[string]bool)
+	for _, section := range imported {
+		for key := range section.Variables {
+			values[key] = true
+		}
+	}
+
+	var missing []string
+	for _, section := range defaults {
+		for key, value := range section.Variables {
+			if isSecretVariable(key) && value != "" && !values[key] {
+				missing = append(missing, key)
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Set the values of the secrets missing from an environment file, an error lists the ones without a value
+func setMissingSecrets(sections []Section, missing []string, secrets map[string]string) error {
+	var empty []string
+	for _, name := range missing {
+		if secrets[name] == "" {
+			empty = append(empty, name)
+			continue
+		}
+		for _, section := range sections {
+			if _, ok := section.Variables[name]; ok {
+				section.Variables[name] = secrets[name]
+			}
+		}
+	}
+	if len(empty) > 0 {
+		return fmt.Errorf("the environment file has no value for the secrets %s", strings.Join(empty, ", "))
+	}
+	return nil
+}
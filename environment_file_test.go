@@ -0,0 +1,120 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Set a variable in the sections, failing if it is not in any of them
+func setTestVariable(t *testing.T, sections []Section, name, value string) {
+	t.Helper()
+	for _, section := range sections {
+		if _, ok := section.Variables[name]; ok {
+			section.Variables[name] = value
+			return
+		}
+	}
+	t.Fatalf("unknown variable %s", name)
+}
+
+// Get a variable of the sections
+func testVariable(sections []Section, name string) string {
+	for _, section := range sections {
+		if value, ok := section.Variables[name]; ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func TestEnvironmentFileRoundTrip(t *testing.T) {
+	a := &App{store: newMemoryEnvironmentStore()}
+	variables, err := a.ReadEnvVariables("docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setTestVariable(t, variables, "API_PORT", "35001")
+	setTestVariable(t, variables, "POSTGRESQL_PASSWORD", "s3cret")
+	environment := Environment{ID: "env1", Platform: "docker", EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "1.0"}, Variables: variables}
+
+	tests := []struct {
+		name           string
+		includeSecrets bool
+		// The secrets that have to be given on import
+		missing []string
+		// The value of POSTGRESQL_PASSWORD after the import, before the missing secrets are given
+		password string
+	}{
+		{name: "without the secrets", missing: []string{"BROKER_PASSWORD", "INGESTOR_HASH", "POSTGRESQL_PASSWORD", "REGISTRY_PASSWORD"}, password: "changeme"},
+		{name: "with the secrets", includeSecrets: true, password: "s3cret"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "environment.json")
+			if err := writeEnvironmentFile(path, environment, test.includeSecrets); err != nil {
+				t.Fatal(err)
+			}
+			imported, missing, err := a.readEnvironmentFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if imported.Platform != "docker" || imported.EnvironmentSetup != environment.EnvironmentSetup {
+				t.Errorf("got %s %+v, want docker %+v", imported.Platform, imported.EnvironmentSetup, environment.EnvironmentSetup)
+			}
+			if value := testVariable(imported.Variables, "API_PORT"); value != "35001" {
+				t.Errorf("API_PORT = %q, want 35001", value)
+			}
+			if value := testVariable(imported.Variables, "POSTGRESQL_PASSWORD"); value != test.password {
+				t.Errorf("POSTGRESQL_PASSWORD = %q, want %q", value, test.password)
+			}
+			if !reflect.DeepEqual(missing, test.missing) {
+				t.Errorf("missing secrets = %q, want %q", missing, test.missing)
+			}
+		})
+	}
+}
+
+func TestSetMissingSecrets(t *testing.T) {
+	sections := []Section{{Name: "database", Variables: map[string]string{"POSTGRESQL_PASSWORD": "changeme", "BROKER_PASSWORD": "changeme"}}}
+	missing := []string{"BROKER_PASSWORD", "POSTGRESQL_PASSWORD"}
+
+	err := setMissingSecrets(sections, missing, map[string]string{"POSTGRESQL_PASSWORD": "s3cret"})
+	if err == nil || !strings.Contains(err.Error(), "BROKER_PASSWORD") || strings.Contains(err.Error(), "POSTGRESQL_PASSWORD") {
+		t.Errorf("expected an error for BROKER_PASSWORD only, got %v", err)
+	}
+
+	if err := setMissingSecrets(sections, missing, map[string]string{"POSTGRESQL_PASSWORD": "s3cret", "BROKER_PASSWORD": "an0ther"}); err != nil {
+		t.Fatal(err)
+	}
+	if value := testVariable(sections, "BROKER_PASSWORD"); value != "an0ther" {
+		t.Errorf("BROKER_PASSWORD = %q, want an0ther", value)
+	}
+}
+
+// The import is refused before anything is installed when the secrets are not given
+func TestImportEnvironmentWithoutSecrets(t *testing.T) {
+	a := &App{store: newMemoryEnvironmentStore()}
+	variables, err := a.ReadEnvVariables("docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	environment := Environment{ID: "env1", Platform: "docker", EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "1.0"}, Variables: variables}
+	path := filepath.Join(t.TempDir(), "environment.json")
+	if err := writeEnvironmentFile(path, environment, false); err != nil {
+		t.Fatal(err)
+	}
+
+	secrets, err := a.GetEnvironmentFileSecrets(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets) == 0 {
+		t.Fatal("expected the secrets to be missing from the file")
+	}
+	if _, err := a.ImportEnvironment(path, nil); err == nil || !strings.Contains(err.Error(), secrets[0]) {
+		t.Errorf("expected an error for the missing secrets, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ServiceStatus is the state of a container (docker, podman) or pod (kubernetes) of an environment
+type ServiceStatus struct {
+	// The compose service or the deployment the pod belongs to
+	Service string `json:"service"`
+	// The name of the container or pod
+	Name string `json:"name"`
+	// running, exited, ... for the containers. Running, Pending, ... for the pods, or the reason their containers wait
+	// (e.g. CrashLoopBackOff)
+	State string `json:"state"`
+	// healthy, unhealthy or starting, empty if the service has no health check
+	Health   string `json:"health"`
+	Restarts int    `json:"restarts"`
+	Image    string `json:"image"`
+}
+
+// EndpointProbe is the result of a request to an access point of an environment
+type EndpointProbe struct {
+	URL string `json:"url"`
+	// The access point answered with a status below 500
+	Healthy    bool  `json:"healthy"`
+	StatusCode int   `json:"statusCode"`
+	DurationMs int64 `json:"durationMs"`
+	// Why the request failed, empty if the access point answered
+	Error string `json:"error"`
+}
+
+// EnvironmentStatus is the health of the services and access points of an installed environment
+type EnvironmentStatus struct {
+	ID string `json:"id"`
+	// The status of the environment on its platform, as in Environment
+	Status   string          `json:"status"`
+	Services []ServiceStatus `json:"services"`
+	// Why the services could not be listed, empty if they were
+	ServicesError string        `json:"servicesError"`
+	DataPortal    EndpointProbe `json:"dataPortal"`
+	ApiGateway    EndpointProbe `json:"apiGateway"`
+	CheckedAt     time.Time     `json:"checkedAt"`
+}
+
+// The time given to an access point to answer
+const endpointProbeTimeout = 5 * time.Second
+
+// The access points of the environments are on this machine or on the network of the cluster, they are reached
+// without the proxy of the network settings
+var environmentHTTPClient = &http.Client{Transport: &http.Transport{}}
+
+// Get the state of the services of an installed environment and check that its access points answer
+func (a *App) GetEnvironmentStatus(id string) (EnvironmentStatus, error) {
+	environment, err := a.store.GetEnvironment(id)
+	if err != nil {
+		return EnvironmentStatus{}, err
+	}
+	driver, err := getPlatformDriver(environment.Platform)
+	if err != nil {
+		return EnvironmentStatus{}, err
+	}
+
+	status := EnvironmentStatus{ID: id, Services: []ServiceStatus{}, CheckedAt: time.Now()}
+
+	// The probes run while the platform is queried
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		status.DataPortal = probeEndpoint(a.ctx, environmentHTTPClient, environment.AccessPoints.DataPortal)
+	}()
+	go func() {
+		defer wg.Done()
+		status.ApiGateway = probeEndpoint(a.ctx, environmentHTTPClient, environment.AccessPoints.ApiGateway)
+	}()
+
+	status.Status = driver.Status(environment)
+	if status.Status == environmentRunning || status.Status == environmentStopped {
+		services, err := driver.Services(environment)
+		if err != nil {
+			status.ServicesError = err.Error()
+		} else {
+			status.Services = services
+		}
+	}
+
+	wg.Wait()
+	return status, nil
+}
+
+// Sort the services by service and name
+func sortServices(services []ServiceStatus) {
+	sort.Slice(services, func(i, j int) bool {
+		if services[i].Service != services[j].Service {
+			return services[i].Service < services[j].Service
+		}
+		return services[i].Name < services[j].Name
+	})
+}
+
+// Send a GET request to the url of an access point with the client
+func probeEndpoint(ctx context.Context, client *http.Client, url string) EndpointProbe {
+	probe := EndpointProbe{URL: url}
+	if url == "" {
+		probe.Error = "the environment has no url for this access point"
+		return probe
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, endpointProbeTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+
+	start := time.Now()
+	response, err := client.Do(request)
+	probe.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	response.Body.Close()
+
+	probe.StatusCode = response.StatusCode
+	probe.Healthy = response.StatusCode < 500
+	return probe
+}
@@ -0,0 +1,166 @@
+package main
+
+import "sort"
+
+// VariableChange is a difference between the variables of an installed environment and the env.env file of the app
+type VariableChange struct {
+	Section  string `json:"section"`
+	Variable string `json:"variable"`
+	// The value in the installed environment, empty for the added variables
+	Value string `json:"value"`
+	// The default when the environment was installed, empty if it is not known
+	OldDefault string `json:"oldDefault"`
+	// The default in the env.env file of the app, empty for the removed variables
+	NewDefault string `json:"newDefault"`
+	// The value was changed from the old default, the upgrade keeps it instead of using the new default
+	Customized bool `json:"customized"`
+}
+
+// VariablesDiff lists what the upgrade of an environment would change in its variables
+type VariablesDiff struct {
+	// The variables of the env.env file missing from the environment, the upgrade adds them with their default
+	Added []VariableChange `json:"added"`
+	// The variables of the environment that are not in the env.env file anymore, the upgrade removes them
+	Removed []VariableChange `json:"removed"`
+	// The variables whose default changed since the environment was installed.
+	// Only known for the environments installed by a version of the app that saved the defaults.
+	Changed []VariableChange `json:"changed"`
+}
+
+// Compare the variables of an installed environment with the current defaults of its platform
+func (a *App) DiffEnvironmentVariables(id string) (VariablesDiff, error) {
+	environment, defaults, err := a.getEnvironmentAndDefaults(id)
+	if err != nil {
+		return VariablesDiff{}, err
+	}
+
+	diff := diffVariables(environment.Variables, defaults)
+	// The secrets never leave the backend
+	for _, changes := range [][]VariableChange{diff.Added, diff.Removed, diff.Changed} {
+		for i := range changes {
+			if changes[i].Value != "" && isSecretVariable(changes[i].Variable) {
+				changes[i].Value = secretMask
+			}
+		}
+	}
+	return diff, nil
+}
+
+// Update the variables of an installed environment to the current defaults of its platform and install it again.
+// The values set by the user are kept, the variables left to their default get the new one.
+func (a *App) UpgradeEnvironment(id string, skipImagesAutoupdate bool) error {
+	environment, defaults, err := a.getEnvironmentAndDefaults(id)
+	if err != nil {
+		return err
+	}
+
+	variables := upgradeVariables(environment.Variables, defaults)
+	_, err = a.InstallEnvironment(environment.Platform, environment.EnvironmentSetup, variables, skipImagesAutoupdate, true)
+	return err
+}
+
+func (a *App) getEnvironmentAndDefaults(id string) (Environment, []Section, error) {
+	environment, err := a.store.GetEnvironment(id)
+	if err != nil {
+		return Environment{}, nil, err
+	}
+	defaults, err := a.ReadEnvVariables(environment.Platform)
+	if err != nil {
+		return Environment{}, nil, err
+	}
+	return environment, defaults, nil
+}
+
+// The value, section and default of every variable of the sections, by name
+type variableState struct {
+	section string
+	value   string
+	// Whether the default is known, it isn't for the environments installed before the details were saved
+	hasDefault   bool
+	defaultValue string
+}
+
+func variableStates(sections []Section) map[string]variableState {
+	states := make(map[string]variableState)
+	for _, section := range sections {
+		for name, value := range section.Variables {
+			states[name] = variableState{section: section.Name, value: value}
+		}
+		for _, details := range section.Details {
+			if state, ok := states[details.Name]; ok && state.section == section.Name {
+				state.hasDefault = true
+				state.defaultValue = details.Default
+				states[details.Name] = state
+			}
+		}
+	}
+	return states
+}
+
+// Compare the variables of an environment with the defaults, the changes are sorted by section and variable
+func diffVariables(installed []Section, defaults []Section) VariablesDiff {
+	current := variableStates(installed)
+	latest := variableStates(defaults)
+	diff := VariablesDiff{Added: []VariableChange{}, Removed: []VariableChange{}, Changed: []VariableChange{}}
+
+	for name, state := range latest {
+		old, ok := current[name]
+		if !ok {
+			diff.Added = append(diff.Added, VariableChange{Section: state.section, Variable: name, NewDefault: state.value})
+			continue
+		}
+		if old.hasDefault && old.defaultValue != state.value {
+			diff.Changed = append(diff.Changed, VariableChange{
+				Section:    state.section,
+				Variable:   name,
+				Value:      old.value,
+				OldDefault: old.defaultValue,
+				NewDefault: state.value,
+				Customized: old.value != old.defaultValue,
+			})
+		}
+	}
+	for name, state := range current {
+		if _, ok := latest[name]; !ok {
+			change := VariableChange{Section: state.section, Variable: name, Value: state.value}
+			if state.hasDefault {
+				change.OldDefault = state.defaultValue
+			}
+			diff.Removed = append(diff.Removed, change)
+		}
+	}
+
+	for _, changes := range [][]VariableChange{diff.Added, diff.Removed, diff.Changed} {
+		sortVariableChanges(changes)
+	}
+	return diff
+}
+
+func sortVariableChanges(changes []VariableChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Section != changes[j].Section {
+			return changes[i].Section < changes[j].Section
+		}
+		return changes[i].Variable < changes[j].Variable
+	})
+}
+
+// Merge the variables of an environment into the defaults: the sections, order and descriptions come from the defaults,
+// the values from the environment unless they were left to a default that changed since
+func upgradeVariables(installed []Section, defaults []Section) []Section {
+	current := variableStates(installed)
+	upgraded := copySections(defaults)
+	for _, section := range upgraded {
+		for name, newDefault := range section.Variables {
+			old, ok := current[name]
+			if !ok {
+				continue
+			}
+			if old.hasDefault && old.value == old.defaultValue && old.defaultValue != newDefault {
+				continue
+			}
+			section.Variables[name] = old.value
+		}
+	}
+	return upgraded
+}
@@ -15,3 +15,6 @@ func RunCommand(cmd *exec.Cmd) (string, error) {
 	}
 	return string(output), nil
 }
+
+// Hide the console window of a command started in the background, only needed on Windows
+func hideConsoleWindow(cmd *exec.Cmd) {}
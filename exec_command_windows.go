@@ -11,10 +11,15 @@ import (
 // Run a command with the console window hidden and return the output as a string
 func RunCommand(cmd *exec.Cmd) (string, error) {
 	// Only needed on Windows to hide the console window
-	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	hideConsoleWindow(cmd)
 	output, err := cmd.Output()
 	if err != nil {
 		return "", err
 	}
 	return string(output), err
 }
+
+// Hide the console window of a command started in the background
+func hideConsoleWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+}
@@ -1,16 +1,19 @@
 import { createStore } from 'vuex'
 
 const initialState = {
-	id: null,	// just a string {environmentSetup.name}-{environmentSetup.version} only used in the frontend
+	id: null,	// the id of the environment, generated by the backend when it is installed
 	platform: null,
 	environmentSetup: {
 		name: null,
 		version: '0.0',
+		host: '',	// the address of the machine in the access points, the default route if empty
 	},
 	// variables is an array of Section objects
 	// class Section {
 	//     name: string;
+	//     description: string;
 	//     variables: {[key: string]: string};
+	//     details: {name: string, description: string, default: string}[];
 	variables: null,
 	skipImagesAutoupdate: false,
 };
@@ -24,8 +27,6 @@ export const store = createStore({
 			checkForUpdatesDone: false,	// flag to indicate if the check for updates has been done
 			homeBannerFullscreen: true,	// true when the app starts, false when the user dismisses the banner
 			populateState: {
-				name: null,
-				version: null,
 				id: null,
 				path: ""
 			},	// the state of the populate process
@@ -49,6 +50,9 @@ export const store = createStore({
 		setEnvironmentContext(state, context) {
 			state.installationState.environmentSetup.context = context;
 		},
+		setEnvironmentHost(state, host) {
+			state.installationState.environmentSetup.host = host;
+		},
 		resetInstallationState(state) {
 			state.installationState = JSON.parse(JSON.stringify(initialState));
 		},
@@ -77,8 +81,6 @@ export const store = createStore({
 		},
 		resetPopulateState(state) {
 			state.populateState = {
-				name: null,
-				version: null,
 				id: null,
 				path: ""
 			};
@@ -0,0 +1,18 @@
+// The variables of a section in the order of the env file, with their description.
+// The variables without details (environments installed by older versions) are sorted by name.
+export function orderedVariables(section) {
+	let ordered = [];
+	let listed = new Set();
+	for (let details of section.details || []) {
+		if (details.name in section.variables && !listed.has(details.name)) {
+			ordered.push({ name: details.name, description: details.description || '' });
+			listed.add(details.name);
+		}
+	}
+	for (let name of Object.keys(section.variables).sort()) {
+		if (!listed.has(name)) {
+			ordered.push({ name: name, description: '' });
+		}
+	}
+	return ordered;
+}
@@ -2,25 +2,67 @@
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
 
+export function AssignPorts(arg1:string,arg2:string,arg3:Array<main.Section>):Promise<Array<main.Section>>;
+
+export function CancelOperation(arg1:string):Promise<void>;
+
+export function CheckConnectivity():Promise<main.ConnectivityResult>;
+
 export function CheckForUpdates():Promise<boolean>;
 
-export function DeleteInstalledEnvironment(arg1:string,arg2:string,arg3:string,arg4:string):Promise<void>;
+export function CheckPlatform(arg1:string):Promise<void>;
+
+export function CreateDiagnosticsBundle(arg1:string):Promise<void>;
+
+export function DeleteInstalledEnvironment(arg1:string):Promise<void>;
+
+export function DiffEnvironmentVariables(arg1:string):Promise<main.VariablesDiff>;
 
 export function DoUpdate():Promise<void>;
 
+export function DownloadLogsBundle(arg1:string):Promise<string>;
+
+export function ExportEnvironment(arg1:string):Promise<string>;
+
+export function ExportImageBundle(arg1:string):Promise<string>;
+
 export function GetAvailablePort():Promise<string>;
 
+export function GetConnectivityTargets():Promise<Array<main.ConnectivityTarget>>;
+
+export function GetEnvironmentFileSecrets(arg1:string):Promise<Array<string>>;
+
+export function GetEnvironmentStatus(arg1:string):Promise<main.EnvironmentStatus>;
+
+export function GetInstalledEnvironment(arg1:string):Promise<main.Environment>;
+
 export function GetInstalledEnvironments():Promise<Array<main.Environment>>;
 
 export function GetIp():Promise<string>;
 
 export function GetKubernetesContexts():Promise<Array<string>>;
 
+export function GetNetworkSettings():Promise<main.NetworkSettings>;
+
+export function GetOfflineMode():Promise<boolean>;
+
+export function GetOperationHistory(arg1:string):Promise<Array<main.OperationRecord>>;
+
+export function GetOperationLog(arg1:string):Promise<string>;
+
+export function GetPortRange():Promise<main.PortRange>;
+
+export function GetRecentOperations(arg1:number):Promise<Array<main.OperationRecord>>;
+
 export function GetReleaseUrl():Promise<string>;
 
+export function GetRunningOperations():Promise<Array<main.RunningOperation>>;
+
 export function GetVersion():Promise<string>;
 
-export function InstallEnvironment(arg1:string,arg2:main.EnvironmentSetup,arg3:Array<main.Section>,arg4:boolean,arg5:boolean):Promise<void>;
+export function ImportEnvironment(arg1:string,arg2:{[key: string]: string}):Promise<string>;
+
+export function InstallEnvironment(arg1:string,arg2:main.EnvironmentSetup,arg3:Array<main.Section>,arg4:boolean,arg5:boolean):Promise<string>;
 
 export function IsDockerInstalled():Promise<boolean>;
 
@@ -28,16 +70,56 @@ export function IsDockerRunning():Promise<boolean>;
 
 export function IsEnvironmentInstalled(arg1:string,arg2:string,arg3:string,arg4:string):Promise<boolean>;
 
-export function IsInternetConnected():Promise<boolean>;
-
 export function IsKubernetesInstalled():Promise<boolean>;
 
-export function IsPortAvailable(arg1:string):Promise<boolean>;
+export function IsPodmanInstalled():Promise<boolean>;
+
+export function IsPodmanRunning():Promise<boolean>;
+
+export function IsPortAvailable(arg1:string,arg2:string):Promise<boolean>;
+
+export function ListHostAddresses():Promise<Array<main.HostAddress>>;
+
+export function LoadImageBundle(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function OpenCACertificatesDialog():Promise<string>;
+
+export function OpenEnvironmentFileDialog():Promise<string>;
 
 export function OpenFolderDialog(arg1:string):Promise<string>;
 
-export function PopulateEnvironment(arg1:string,arg2:string,arg3:string,arg4:string):Promise<void>;
+export function OpenImageBundleDialog():Promise<string>;
+
+export function PopulateEnvironment(arg1:string,arg2:string):Promise<void>;
+
+export function PruneMissingEnvironments():Promise<Array<main.Environment>>;
 
 export function ReadEnvVariables(arg1:string):Promise<Array<main.Section>>;
 
+export function RestartEnvironment(arg1:string):Promise<void>;
+
+export function RestartService(arg1:string,arg2:string):Promise<void>;
+
+export function SaveDiagnosticsBundleDialog():Promise<string>;
+
+export function SetConnectivityTargets(arg1:Array<main.ConnectivityTarget>):Promise<void>;
+
+export function SetNetworkSettings(arg1:main.NetworkSettings):Promise<void>;
+
+export function SetOfflineMode(arg1:boolean):Promise<void>;
+
+export function SetPortRange(arg1:main.PortRange):Promise<void>;
+
 export function SpecifyPlatformPath(arg1:string):Promise<string>;
+
+export function StartEnvironment(arg1:string):Promise<void>;
+
+export function StopEnvironment(arg1:string):Promise<void>;
+
+export function StopServiceLogs(arg1:string):Promise<void>;
+
+export function StreamServiceLogs(arg1:string,arg2:string,arg3:boolean,arg4:number):Promise<string>;
+
+export function UpgradeEnvironment(arg1:string,arg2:boolean):Promise<void>;
+
+export function ValidateVariables(arg1:string,arg2:Array<main.Section>):Promise<Array<main.VariableError>>;
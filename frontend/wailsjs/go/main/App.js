@@ -2,22 +2,74 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AssignPorts(arg1, arg2, arg3) {
+  return window['go']['main']['App']['AssignPorts'](arg1, arg2, arg3);
+}
+
+export function CancelOperation(arg1) {
+  return window['go']['main']['App']['CancelOperation'](arg1);
+}
+
+export function CheckConnectivity() {
+  return window['go']['main']['App']['CheckConnectivity']();
+}
+
 export function CheckForUpdates() {
   return window['go']['main']['App']['CheckForUpdates']();
 }
 
-export function DeleteInstalledEnvironment(arg1, arg2, arg3, arg4) {
-  return window['go']['main']['App']['DeleteInstalledEnvironment'](arg1, arg2, arg3, arg4);
+export function CheckPlatform(arg1) {
+  return window['go']['main']['App']['CheckPlatform'](arg1);
+}
+
+export function CreateDiagnosticsBundle(arg1) {
+  return window['go']['main']['App']['CreateDiagnosticsBundle'](arg1);
+}
+
+export function DeleteInstalledEnvironment(arg1) {
+  return window['go']['main']['App']['DeleteInstalledEnvironment'](arg1);
+}
+
+export function DiffEnvironmentVariables(arg1) {
+  return window['go']['main']['App']['DiffEnvironmentVariables'](arg1);
 }
 
 export function DoUpdate() {
   return window['go']['main']['App']['DoUpdate']();
 }
 
+export function DownloadLogsBundle(arg1) {
+  return window['go']['main']['App']['DownloadLogsBundle'](arg1);
+}
+
+export function ExportEnvironment(arg1) {
+  return window['go']['main']['App']['ExportEnvironment'](arg1);
+}
+
+export function ExportImageBundle(arg1) {
+  return window['go']['main']['App']['ExportImageBundle'](arg1);
+}
+
 export function GetAvailablePort() {
   return window['go']['main']['App']['GetAvailablePort']();
 }
 
+export function GetConnectivityTargets() {
+  return window['go']['main']['App']['GetConnectivityTargets']();
+}
+
+export function GetEnvironmentFileSecrets(arg1) {
+  return window['go']['main']['App']['GetEnvironmentFileSecrets'](arg1);
+}
+
+export function GetEnvironmentStatus(arg1) {
+  return window['go']['main']['App']['GetEnvironmentStatus'](arg1);
+}
+
+export function GetInstalledEnvironment(arg1) {
+  return window['go']['main']['App']['GetInstalledEnvironment'](arg1);
+}
+
 export function GetInstalledEnvironments() {
   return window['go']['main']['App']['GetInstalledEnvironments']();
 }
@@ -30,14 +82,46 @@ export function GetKubernetesContexts() {
   return window['go']['main']['App']['GetKubernetesContexts']();
 }
 
+export function GetNetworkSettings() {
+  return window['go']['main']['App']['GetNetworkSettings']();
+}
+
+export function GetOfflineMode() {
+  return window['go']['main']['App']['GetOfflineMode']();
+}
+
+export function GetOperationHistory(arg1) {
+  return window['go']['main']['App']['GetOperationHistory'](arg1);
+}
+
+export function GetOperationLog(arg1) {
+  return window['go']['main']['App']['GetOperationLog'](arg1);
+}
+
+export function GetPortRange() {
+  return window['go']['main']['App']['GetPortRange']();
+}
+
+export function GetRecentOperations(arg1) {
+  return window['go']['main']['App']['GetRecentOperations'](arg1);
+}
+
 export function GetReleaseUrl() {
   return window['go']['main']['App']['GetReleaseUrl']();
 }
 
+export function GetRunningOperations() {
+  return window['go']['main']['App']['GetRunningOperations']();
+}
+
 export function GetVersion() {
   return window['go']['main']['App']['GetVersion']();
 }
 
+export function ImportEnvironment(arg1, arg2) {
+  return window['go']['main']['App']['ImportEnvironment'](arg1, arg2);
+}
+
 export function InstallEnvironment(arg1, arg2, arg3, arg4, arg5) {
   return window['go']['main']['App']['InstallEnvironment'](arg1, arg2, arg3, arg4, arg5);
 }
@@ -54,30 +138,110 @@ export function IsEnvironmentInstalled(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['IsEnvironmentInstalled'](arg1, arg2, arg3, arg4);
 }
 
-export function IsInternetConnected() {
-  return window['go']['main']['App']['IsInternetConnected']();
-}
-
 export function IsKubernetesInstalled() {
   return window['go']['main']['App']['IsKubernetesInstalled']();
 }
 
-export function IsPortAvailable(arg1) {
-  return window['go']['main']['App']['IsPortAvailable'](arg1);
+export function IsPodmanInstalled() {
+  return window['go']['main']['App']['IsPodmanInstalled']();
+}
+
+export function IsPodmanRunning() {
+  return window['go']['main']['App']['IsPodmanRunning']();
+}
+
+export function IsPortAvailable(arg1, arg2) {
+  return window['go']['main']['App']['IsPortAvailable'](arg1, arg2);
+}
+
+export function ListHostAddresses() {
+  return window['go']['main']['App']['ListHostAddresses']();
+}
+
+export function LoadImageBundle(arg1, arg2, arg3) {
+  return window['go']['main']['App']['LoadImageBundle'](arg1, arg2, arg3);
+}
+
+export function OpenCACertificatesDialog() {
+  return window['go']['main']['App']['OpenCACertificatesDialog']();
+}
+
+export function OpenEnvironmentFileDialog() {
+  return window['go']['main']['App']['OpenEnvironmentFileDialog']();
 }
 
 export function OpenFolderDialog(arg1) {
   return window['go']['main']['App']['OpenFolderDialog'](arg1);
 }
 
-export function PopulateEnvironment(arg1, arg2, arg3, arg4) {
-  return window['go']['main']['App']['PopulateEnvironment'](arg1, arg2, arg3, arg4);
+export function OpenImageBundleDialog() {
+  return window['go']['main']['App']['OpenImageBundleDialog']();
+}
+
+export function PopulateEnvironment(arg1, arg2) {
+  return window['go']['main']['App']['PopulateEnvironment'](arg1, arg2);
+}
+
+export function PruneMissingEnvironments() {
+  return window['go']['main']['App']['PruneMissingEnvironments']();
 }
 
 export function ReadEnvVariables(arg1) {
   return window['go']['main']['App']['ReadEnvVariables'](arg1);
 }
 
+export function RestartEnvironment(arg1) {
+  return window['go']['main']['App']['RestartEnvironment'](arg1);
+}
+
+export function RestartService(arg1, arg2) {
+  return window['go']['main']['App']['RestartService'](arg1, arg2);
+}
+
+export function SaveDiagnosticsBundleDialog() {
+  return window['go']['main']['App']['SaveDiagnosticsBundleDialog']();
+}
+
+export function SetConnectivityTargets(arg1) {
+  return window['go']['main']['App']['SetConnectivityTargets'](arg1);
+}
+
+export function SetNetworkSettings(arg1) {
+  return window['go']['main']['App']['SetNetworkSettings'](arg1);
+}
+
+export function SetOfflineMode(arg1) {
+  return window['go']['main']['App']['SetOfflineMode'](arg1);
+}
+
+export function SetPortRange(arg1) {
+  return window['go']['main']['App']['SetPortRange'](arg1);
+}
+
 export function SpecifyPlatformPath(arg1) {
   return window['go']['main']['App']['SpecifyPlatformPath'](arg1);
 }
+
+export function StartEnvironment(arg1) {
+  return window['go']['main']['App']['StartEnvironment'](arg1);
+}
+
+export function StopEnvironment(arg1) {
+  return window['go']['main']['App']['StopEnvironment'](arg1);
+}
+
+export function StopServiceLogs(arg1) {
+  return window['go']['main']['App']['StopServiceLogs'](arg1);
+}
+
+export function StreamServiceLogs(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['StreamServiceLogs'](arg1, arg2, arg3, arg4);
+}
+
+export function UpgradeEnvironment(arg1, arg2) {
+  return window['go']['main']['App']['UpgradeEnvironment'](arg1, arg2);
+}
+
+export function ValidateVariables(arg1, arg2) {
+  return window['go']['main']['App']['ValidateVariables'](arg1, arg2);
+}
@@ -1,5 +1,174 @@
 export namespace main {
 	
+	export class VariableError {
+	    section: string;
+	    variable: string;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new VariableError(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.section = source["section"];
+	        this.variable = source["variable"];
+	        this.message = source["message"];
+	    }
+	}
+	export class HostAddress {
+	    interface: string;
+	    address: string;
+	    ipv6: boolean;
+	    loopback: boolean;
+	    hint: string;
+	    default: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new HostAddress(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.address = source["address"];
+	        this.ipv6 = source["ipv6"];
+	        this.loopback = source["loopback"];
+	        this.hint = source["hint"];
+	        this.default = source["default"];
+	    }
+	}
+	export class RunningOperation {
+	    id: string;
+	    kind: string;
+	    environmentId: string;
+	    platform: string;
+	    name: string;
+	    version: string;
+	    context: string;
+	    // Go type: time
+	    startedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new RunningOperation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.kind = source["kind"];
+	        this.environmentId = source["environmentId"];
+	        this.platform = source["platform"];
+	        this.name = source["name"];
+	        this.version = source["version"];
+	        this.context = source["context"];
+	        this.startedAt = this.convertValues(source["startedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PortRange {
+	    start: number;
+	    end: number;
+	    strict: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new PortRange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.strict = source["strict"];
+	    }
+	}
+	export class OperationRecord {
+	    id: string;
+	    kind: string;
+	    environmentId: string;
+	    platform: string;
+	    name: string;
+	    version: string;
+	    context: string;
+	    // Go type: time
+	    startedAt: any;
+	    // Go type: time
+	    endedAt: any;
+	    status: string;
+	    error: string;
+	    pid: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new OperationRecord(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.kind = source["kind"];
+	        this.environmentId = source["environmentId"];
+	        this.platform = source["platform"];
+	        this.name = source["name"];
+	        this.version = source["version"];
+	        this.context = source["context"];
+	        this.startedAt = this.convertValues(source["startedAt"], null);
+	        this.endedAt = this.convertValues(source["endedAt"], null);
+	        this.status = source["status"];
+	        this.error = source["error"];
+	        this.pid = source["pid"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class NetworkSettings {
+	    proxyUrl: string;
+	    noProxy: string;
+	    caCertificatesPath: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new NetworkSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.proxyUrl = source["proxyUrl"];
+	        this.noProxy = source["noProxy"];
+	        this.caCertificatesPath = source["caCertificatesPath"];
+	    }
+	}
 	export class EposAccessPoints {
 	    apiGateway: string;
 	    dataPortal: string;
@@ -14,9 +183,27 @@ export namespace main {
 	        this.dataPortal = source["dataPortal"];
 	    }
 	}
+	export class VariableDetails {
+	    name: string;
+	    description: string;
+	    default: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new VariableDetails(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.default = source["default"];
+	    }
+	}
 	export class Section {
 	    name: string;
+	    description?: string;
 	    variables: {[key: string]: string};
+	    details?: VariableDetails[];
 	
 	    static createFrom(source: any = {}) {
 	        return new Section(source);
@@ -25,13 +212,34 @@ export namespace main {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.name = source["name"];
+	        this.description = source["description"];
 	        this.variables = source["variables"];
+	        this.details = this.convertValues(source["details"], VariableDetails);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class EnvironmentSetup {
 	    name: string;
 	    version: string;
 	    context: string;
+	    host: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new EnvironmentSetup(source);
@@ -42,13 +250,16 @@ export namespace main {
 	        this.name = source["name"];
 	        this.version = source["version"];
 	        this.context = source["context"];
+	        this.host = source["host"];
 	    }
 	}
 	export class Environment {
+	    id: string;
 	    platform: string;
 	    environmentSetup: EnvironmentSetup;
 	    variables: Section[];
 	    accessPoints: EposAccessPoints;
+	    status: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new Environment(source);
@@ -56,10 +267,232 @@ export namespace main {
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
 	        this.platform = source["platform"];
 	        this.environmentSetup = this.convertValues(source["environmentSetup"], EnvironmentSetup);
 	        this.variables = this.convertValues(source["variables"], Section);
 	        this.accessPoints = this.convertValues(source["accessPoints"], EposAccessPoints);
+	        this.status = source["status"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class EndpointProbe {
+	    url: string;
+	    healthy: boolean;
+	    statusCode: number;
+	    durationMs: number;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new EndpointProbe(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.healthy = source["healthy"];
+	        this.statusCode = source["statusCode"];
+	        this.durationMs = source["durationMs"];
+	        this.error = source["error"];
+	    }
+	}
+	export class ServiceStatus {
+	    service: string;
+	    name: string;
+	    state: string;
+	    health: string;
+	    restarts: number;
+	    image: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ServiceStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.service = source["service"];
+	        this.name = source["name"];
+	        this.state = source["state"];
+	        this.health = source["health"];
+	        this.restarts = source["restarts"];
+	        this.image = source["image"];
+	    }
+	}
+	export class EnvironmentStatus {
+	    id: string;
+	    status: string;
+	    services: ServiceStatus[];
+	    servicesError: string;
+	    dataPortal: EndpointProbe;
+	    apiGateway: EndpointProbe;
+	    // Go type: time
+	    checkedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new EnvironmentStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.status = source["status"];
+	        this.services = this.convertValues(source["services"], ServiceStatus);
+	        this.servicesError = source["servicesError"];
+	        this.dataPortal = this.convertValues(source["dataPortal"], EndpointProbe);
+	        this.apiGateway = this.convertValues(source["apiGateway"], EndpointProbe);
+	        this.checkedAt = this.convertValues(source["checkedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ConnectivityTarget {
+	    name: string;
+	    url: string;
+	    feature: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConnectivityTarget(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.url = source["url"];
+	        this.feature = source["feature"];
+	    }
+	}
+	export class VariableChange {
+	    section: string;
+	    variable: string;
+	    value: string;
+	    oldDefault: string;
+	    newDefault: string;
+	    customized: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new VariableChange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.section = source["section"];
+	        this.variable = source["variable"];
+	        this.value = source["value"];
+	        this.oldDefault = source["oldDefault"];
+	        this.newDefault = source["newDefault"];
+	        this.customized = source["customized"];
+	    }
+	}
+	export class VariablesDiff {
+	    added: VariableChange[];
+	    removed: VariableChange[];
+	    changed: VariableChange[];
+	
+	    static createFrom(source: any = {}) {
+	        return new VariablesDiff(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.added = this.convertValues(source["added"], VariableChange);
+	        this.removed = this.convertValues(source["removed"], VariableChange);
+	        this.changed = this.convertValues(source["changed"], VariableChange);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ConnectivityProbe {
+	    name: string;
+	    url: string;
+	    feature: string;
+	    reachable: boolean;
+	    statusCode: number;
+	    durationMs: number;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConnectivityProbe(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.url = source["url"];
+	        this.feature = source["feature"];
+	        this.reachable = source["reachable"];
+	        this.statusCode = source["statusCode"];
+	        this.durationMs = source["durationMs"];
+	        this.error = source["error"];
+	    }
+	}
+	export class ConnectivityResult {
+	    offline: boolean;
+	    probes: ConnectivityProbe[];
+	    images: boolean;
+	    updates: boolean;
+	    // Go type: time
+	    checkedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConnectivityResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.offline = source["offline"];
+	        this.probes = this.convertValues(source["probes"], ConnectivityProbe);
+	        this.images = source["images"];
+	        this.updates = source["updates"];
+	        this.checkedAt = this.convertValues(source["checkedAt"], null);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -6,9 +6,11 @@ require (
 	github.com/epos-eu/opensource-docker v0.0.0-20250203131413-e8ab65a2354e
 	github.com/epos-eu/opensource-kubernetes v0.0.0-20250203131538-1194300d66ee
 	github.com/google/go-github/v60 v60.0.0
+	github.com/joho/godotenv v1.5.1
 	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/minio/selfupdate v0.6.0
 	github.com/wailsapp/wails/v2 v2.9.2
+	golang.org/x/net v0.25.0
 )
 
 require (
@@ -26,7 +28,6 @@ require (
 	github.com/hashicorp/go-version v1.6.0 // indirect
 	github.com/jchv/go-winloader v0.0.0-20210711035445-715c2860da7e // indirect
 	github.com/jedib0t/go-pretty/v6 v6.5.4 // indirect
-	github.com/joho/godotenv v1.5.1 // indirect
 	github.com/labstack/echo/v4 v4.10.2 // indirect
 	github.com/labstack/gommon v0.4.0 // indirect
 	github.com/leaanthony/go-ansi-parser v1.6.0 // indirect
@@ -47,7 +48,6 @@ require (
 	github.com/wailsapp/mimetype v1.4.1 // indirect
 	golang.org/x/crypto v0.23.0 // indirect
 	golang.org/x/exp v0.0.0-20230522175609-2e198f4a06a1 // indirect
-	golang.org/x/net v0.25.0 // indirect
 	golang.org/x/oauth2 v0.7.0 // indirect
 	golang.org/x/sys v0.20.0 // indirect
 	golang.org/x/text v0.15.0 // indirect
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	dockerMethods "github.com/epos-eu/opensource-docker/cmd/methods"
+)
+
+// HostAddress is an address of an interface of the machine, one of those the access points of an environment can use
+type HostAddress struct {
+	Interface string `json:"interface"`
+	Address   string `json:"address"`
+	IPv6      bool   `json:"ipv6"`
+	// Only this machine can reach the environment on the address
+	Loopback bool `json:"loopback"`
+	// "vpn" or "bridge" when the name of the interface tells it is a vpn or a virtual bridge (e.g. docker0),
+	// the other machines usually can't reach those addresses. Empty for the other interfaces.
+	Hint string `json:"hint"`
+	// The address of the default route, the one used when the environment has no host
+	Default bool `json:"default"`
+}
+
+// The prefixes of the names of the interfaces created by the vpns and by the virtual networks of the containers and
+// the virtual machines, on linux, macOS and windows
+var (
+	vpnInterfacePrefixes    = []string{"tun", "tap", "utun", "wg", "ppp", "ipsec", "tailscale", "zt", "nordlynx"}
+	bridgeInterfacePrefixes = []string{"docker", "br-", "veth", "virbr", "cni", "flannel", "cali", "vmnet", "vboxnet", "vethernet", "podman", "lxc", "lxd", "bridge"}
+)
+
+// The ports of the services in the compose file of the docker cmd, e.g. - "${DATA_PORTAL_PORT}:80", with the name of the variable
+var composePortPattern = regexp.MustCompile(`(?m)^(\s*-\s*")(\$\{(\w+)\}:\d+")`)
+
+// List the addresses of the interfaces that are up, to choose the host of an environment. The address of the default
+// route comes first and the loopback addresses last. The IPv6 link-local addresses are left out, the browsers can't use them.
+func (a *App) ListHostAddresses() ([]HostAddress, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	defaultAddress := defaultRouteAddress()
+
+	addresses := []HostAddress{}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		ifaceAddresses, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, address := range ifaceAddresses {
+			ipNet, ok := address.(*net.IPNet)
+			if !ok || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			addresses = append(addresses, HostAddress{
+				Interface: iface.Name,
+				Address:   ipNet.IP.String(),
+				IPv6:      ipNet.IP.To4() == nil,
+				Loopback:  ipNet.IP.IsLoopback(),
+				Hint:      interfaceHint(iface.Name),
+				Default:   defaultAddress != nil && ipNet.IP.Equal(defaultAddress),
+			})
+		}
+	}
+
+	sort.SliceStable(addresses, func(i, j int) bool {
+		return hostAddressRank(addresses[i]) < hostAddressRank(addresses[j])
+	})
+	return addresses, nil
+}
+
+// The order of the addresses in the list: the default route, the other interfaces, the vpns and bridges, the loopback
+func hostAddressRank(address HostAddress) int {
+	switch {
+	case address.Default:
+		return 0
+	case address.Loopback:
+		return 3
+	case address.Hint != "":
+		return 2
+	}
+	return 1
+}
+
+// Tell from its name if the interface is a vpn or a virtual bridge, empty if it is neither
+func interfaceHint(name string) string {
+	name = strings.ToLower(name)
+	for _, prefix := range vpnInterfacePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return "vpn"
+		}
+	}
+	if strings.Contains(name, "vpn") {
+		return "vpn"
+	}
+	for _, prefix := range bridgeInterfacePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return "bridge"
+		}
+	}
+	return ""
+}
+
+// The address of the interface of the default route, nil if there is none (e.g. on a network without internet).
+// Dialing UDP sends nothing, it only picks the interface.
+func defaultRouteAddress() net.IP {
+	conn, err := net.Dial("udp", "8.8.8.8:80")
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP
+}
+
+// Check the host chosen for an environment: localhost or an address of this machine, only for docker and podman
+func checkEnvironmentHost(platform, host string) error {
+	if host == "" {
+		return nil
+	}
+	if platform == "kubernetes" {
+		return errors.New("the host can't be chosen for kubernetes, the access points are the ones of the cluster")
+	}
+	if host == "localhost" {
+		return nil
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("the host must be localhost or an ip address of this machine: %s", host)
+	}
+	if !isMachineAddress(ip) {
+		return fmt.Errorf("%s is not an address of this machine", host)
+	}
+	return nil
+}
+
+// If the ip is the address of an interface of this machine
+func isMachineAddress(ip net.IP) bool {
+	addresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, address := range addresses {
+		if ipNet, ok := address.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// The host as written in a url, the IPv6 addresses are in brackets
+func urlHost(host string) string {
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+// Set the host of the environment as API_HOST_ENV, the docker cmd uses it instead of the address of the default route
+// in the variables of the services and in the access points
+func useEnvironmentHost(environment Environment) {
+	if host := environment.EnvironmentSetup.Host; host != "" {
+		os.Setenv("API_HOST_ENV", urlHost(host))
+	}
+}
+
+// The address of the environment in its access points, as written in a url: its host, or the address of the machine
+// when it has none
+func environmentAddress(environment Environment) (string, error) {
+	host := environment.EnvironmentSetup.Host
+	if host == "" {
+		address, err := machineAddress()
+		if err != nil {
+			return "", fmt.Errorf("error getting the address of the machine: %w", err)
+		}
+		host = address
+	}
+	return urlHost(host), nil
+}
+
+// Set the variables with the address of the environment used by the services and the access points, like SetupIPs of
+// the docker cmd does. SetupIPs can't be used without a default route (e.g. offline), it crashes looking for it.
+// The env file of the environment must be loaded first.
+func setupHostVariables(address string) {
+	os.Setenv("API_HOST_ENV", address)
+	os.Setenv("API_HOST", "http://"+address+":"+os.Getenv("API_PORT")+os.Getenv("DEPLOY_PATH")+os.Getenv("API_PATH"))
+	os.Setenv("EXECUTE_HOST", "http://"+address+":"+os.Getenv("API_PORT"))
+	os.Setenv("HOST", "http://"+address+":"+os.Getenv("DATA_PORTAL_PORT"))
+	os.Setenv("LOCAL_IP", address)
+}
+
+// The populate and the delete of the docker cmd always look for the address of the default route, and crash without one
+func checkDefaultRoute() error {
+	if defaultRouteAddress() == nil {
+		return errors.New("there is no default route: the docker cmd needs a network connection to find the address of the machine")
+	}
+	return nil
+}
+
+// The address the ports of the environment are published on when its host is a loopback address,
+// empty if they are published on all the interfaces
+func loopbackBindAddress(host string) string {
+	if host == "localhost" {
+		return "127.0.0.1"
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return urlHost(host)
+	}
+	return ""
+}
+
+// The compose file of the docker cmd, its ports only published on the loopback address if it is the host of the environment
+func environmentComposeFile(environment Environment) []byte {
+	compose := dockerMethods.GetDockerComposeEmbed()
+	if address := loopbackBindAddress(environment.EnvironmentSetup.Host); address != "" {
+		compose = composePortPattern.ReplaceAll(compose, []byte("${1}"+address+":${2}"))
+	}
+	return compose
+}
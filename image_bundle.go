@@ -0,0 +1,319 @@
+package main
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	dockerMethods "github.com/epos-eu/opensource-docker/cmd/methods"
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// The setting of the offline mode, "true" when it is enabled
+const offlineModeSetting = "offlineMode"
+
+// The message bus image is referenced without the registry of the EPOS images
+const messageBusImageVariable = "MESSAGE_BUS_IMAGE"
+
+// Get if the offline mode is enabled: the connectivity and update checks are skipped and the installs only use
+// the images already loaded from a bundle (see LoadImageBundle)
+func (a *App) GetOfflineMode() (bool, error) {
+	value, err := a.store.GetSetting(offlineModeSetting)
+	if err != nil {
+		return false, err
+	}
+	return value == "true", nil
+}
+
+// Enable or disable the offline mode
+func (a *App) SetOfflineMode(enabled bool) error {
+	return a.store.SetSetting(offlineModeSetting, fmt.Sprintf("%t", enabled))
+}
+
+// The offline mode, disabled if the setting can't be read
+func (a *App) isOfflineMode() bool {
+	offline, err := a.GetOfflineMode()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading the offline mode:", err)
+	}
+	return offline
+}
+
+// Ask where to save the images referenced by the default configuration of the platform, then pull them and save
+// them in a tarball that LoadImageBundle loads on a machine without internet. The images are those of the env file of
+// this version of the app, the bundle is named after it. Returns the path of the file, empty if the dialog was cancelled.
+func (a *App) ExportImageBundle(platform string) (string, error) {
+	path, err := wailsRuntime.SaveFileDialog(a.ctx, wailsRuntime.SaveDialogOptions{
+		Title:           "Save the images",
+		DefaultFilename: "epos-images-" + platform + "-" + VERSION + ".tar",
+		Filters:         imageBundleFilters(),
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+
+	return path, a.exportImageBundle(platform, path)
+}
+
+// Pull the images of the default configuration of the platform and save them in a tarball at path
+func (a *App) exportImageBundle(platform, path string) error {
+	driver, err := getPlatformDriver(platform)
+	if err != nil {
+		return err
+	}
+	variables, err := driver.DefaultVariables()
+	if err != nil {
+		return err
+	}
+
+	return a.runImagesOperation("export-images", driver, workerRequest{
+		Path:   path,
+		Images: imagesOfVariables(variables),
+	})
+}
+
+// Ask for an image bundle to load, returns an empty path if the dialog was cancelled
+func (a *App) OpenImageBundleDialog() (string, error) {
+	return wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title:   "Select the images",
+		Filters: imageBundleFilters(),
+	})
+}
+
+// Load the images of a bundle saved by ExportImageBundle in docker or podman, so that the platform can install
+// the environments without internet. The kubernetes clusters pull the images on every start: the images are
+// pushed to the registry (e.g. localhost:5000), which must then be set as DOCKER_REGISTRY of the environments.
+func (a *App) LoadImageBundle(platform, path, registry string) error {
+	driver, err := getPlatformDriver(platform)
+	if err != nil {
+		return err
+	}
+	if platform == "kubernetes" && registry == "" {
+		return errors.New("a registry is needed to load the images for kubernetes, the cluster pulls them on every start")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	return a.runImagesOperation("load-images", driver, workerRequest{
+		Path:     path,
+		Registry: registry,
+	})
+}
+
+// Run an export or a load of the images, its output is sent to the frontend and recorded in the history
+// like the one of the operations on the environments
+func (a *App) runImagesOperation(kind string, driver PlatformDriver, request workerRequest) error {
+	request.Operation = kind
+	request.Environment = Environment{Platform: driver.Name()}
+
+	ctx, op := a.startOperation(kind, request.Environment)
+	defer a.endOperation(op)
+	_, err := a.runOperation(ctx, op, driver, request)
+	return err
+}
+
+func imageBundleFilters() []wailsRuntime.FileFilter {
+	return []wailsRuntime.FileFilter{{DisplayName: "Image bundle (*.tar)", Pattern: "*.tar"}}
+}
+
+// The images used by the services, from the *_IMAGE variables and the registry in DOCKER_REGISTRY
+func imagesOfVariables(sections []Section) []string {
+	registry := "epos"
+	for _, section := range sections {
+		if value, ok := section.Variables["DOCKER_REGISTRY"]; ok && value != "" {
+			registry = value
+		}
+	}
+
+	var images []string
+	for _, section := range sections {
+		for _, name := range orderedVariableNames(section) {
+			value := section.Variables[name]
+			if !strings.HasSuffix(name, "_IMAGE") || value == "" {
+				continue
+			}
+			if name == messageBusImageVariable {
+				images = append(images, value)
+			} else {
+				images = append(images, registry+"/"+value)
+			}
+		}
+	}
+	return images
+}
+
+// The tool managing the images of the platform, kubernetes uses docker to save, load and push them
+func imagesTool(platform string) string {
+	if platform == "podman" {
+		return "podman"
+	}
+	return "docker"
+}
+
+// The images that are not in docker or podman, those an offline install can't get
+func missingImages(tool string, images []string) []string {
+	var missing []string
+	for _, image := range images {
+		cmd := exec.Command(tool, "image", "inspect", "--format", "{{.Id}}", image)
+		hideConsoleWindow(cmd)
+		if err := cmd.Run(); err != nil {
+			missing = append(missing, image)
+		}
+	}
+	return missing
+}
+
+// Pull the images and save them all in one tarball at path, run by the worker
+func saveImages(ctx context.Context, tool string, images []string, path string) error {
+	for _, image := range images {
+		dockerMethods.PrintTask("Pulling " + image)
+		if err := dockerMethods.ExecuteCommand(exec.CommandContext(ctx, tool, "pull", image)); err != nil {
+			return err
+		}
+	}
+
+	dockerMethods.PrintTask("Saving the images to " + path)
+	args := []string{"save", "-o", path}
+	if tool == "podman" {
+		// podman only saves one image per archive by default
+		args = append(args, "--multi-image-archive")
+	}
+	if err := dockerMethods.ExecuteCommand(exec.CommandContext(ctx, tool, append(args, images...)...)); err != nil {
+		// Don't leave half a bundle
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// Load the images of the tarball at path, then push them to the registry if there is one. Run by the worker.
+func loadImages(ctx context.Context, tool string, path string, registry string) error {
+	dockerMethods.PrintTask("Loading the images from " + path)
+	if err := dockerMethods.ExecuteCommand(exec.CommandContext(ctx, tool, "load", "-i", path)); err != nil {
+		return err
+	}
+	if registry == "" {
+		return nil
+	}
+
+	images, err := imageBundleTags(path)
+	if err != nil {
+		return err
+	}
+	for _, image := range images {
+		target := registryImage(registry, image)
+		dockerMethods.PrintTask("Pushing " + target)
+		if err := dockerMethods.ExecuteCommand(exec.CommandContext(ctx, tool, "tag", image, target)); err != nil {
+			return err
+		}
+		if err := dockerMethods.ExecuteCommand(exec.CommandContext(ctx, tool, "push", target)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The image in another registry, e.g. epos/data-portal:1.0.3 in localhost:5000 is localhost:5000/data-portal:1.0.3
+func registryImage(registry, image string) string {
+	return registry + "/" + path.Base(image)
+}
+
+// Read the images of a tarball saved by docker or podman from its manifest.json
+func imageBundleTags(bundlePath string) ([]string, error) {
+	file, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := tar.NewReader(file)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("not an image bundle, there is no manifest.json: %s", bundlePath)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Name != "manifest.json" {
+			continue
+		}
+
+		var manifest []struct {
+			RepoTags []string
+		}
+		if err := json.NewDecoder(reader).Decode(&manifest); err != nil {
+			return nil, fmt.Errorf("error reading the manifest of the bundle: %w", err)
+		}
+		var images []string
+		for _, entry := range manifest {
+			images = append(images, entry.RepoTags...)
+		}
+		return images, nil
+	}
+}
+
+// Answers the requests of the cmds to the tags of the images on Docker Hub in offline mode. The kubernetes cmd looks up
+// the latest tags even when the update of the images is skipped: they are answered with the tags in the variables,
+// so that the images stay the ones loaded from the bundle. The other requests go to next.
+type offlineImageTagsTransport struct {
+	next http.RoundTripper
+}
+
+func (t offlineImageTagsTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.URL.Host != "hub.docker.com" {
+		return t.next.RoundTrip(request)
+	}
+
+	// The path is /v2/repositories/<namespace>/<repository>/tags
+	parts := strings.Split(strings.Trim(request.URL.Path, "/"), "/")
+	if len(parts) != 5 || parts[4] != "tags" {
+		return nil, fmt.Errorf("offline mode, can't reach %s", request.URL)
+	}
+	tag := imageTagInEnvironment(parts[3])
+	if tag == "" {
+		return nil, fmt.Errorf("offline mode, no tag of %s in the variables", parts[3])
+	}
+
+	// The cmds read the second result, the first one is usually latest
+	body, err := json.Marshal(map[string]any{
+		"results": []map[string]string{{"name": tag}, {"name": tag}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(string(body))),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}, nil
+}
+
+// The tag of the repository in the *_IMAGE variables loaded in the process by the cmds, empty if there is none
+func imageTagInEnvironment(repository string) string {
+	for _, variable := range os.Environ() {
+		name, value, _ := strings.Cut(variable, "=")
+		if !strings.HasSuffix(name, "_IMAGE") {
+			continue
+		}
+		if image, tag, ok := strings.Cut(value, ":"); ok && image == repository {
+			return tag
+		}
+	}
+	return ""
+}
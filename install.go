@@ -1,20 +1,10 @@
 package main
 
 import (
-	"bufio"
-
-	dockerMethods "github.com/epos-eu/opensource-docker/cmd/methods"
-	kubernetesMethods "github.com/epos-eu/opensource-kubernetes/cmd/methods"
-
-	"database/sql"
-	"encoding/json"
+	"context"
 	"fmt"
 	"os"
 	"strings"
-
-	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 type EposAccessPoints struct {
@@ -22,199 +12,183 @@ type EposAccessPoints struct {
 	DataPortal string `json:"dataPortal"`
 }
 
-func (a *App) InstallEnvironment(platform string, environmentSetup EnvironmentSetup, variables []Section, skipImagesAutoupdate bool, isEdit bool) error {
-
-	// print for debugging
-	fmt.Println("Platform: ", platform)
-	fmt.Println("EnvironmentSetup: ", environmentSetup)
-	fmt.Println("Variables: ", variables)
-	fmt.Println("SkipImagesAutoupdate: ", skipImagesAutoupdate)
-	fmt.Println("IsEdit: ", isEdit)
-
-	// Set the flag for the auto update of the images
-	autoUpdateImages := !skipImagesAutoupdate
-	var err error
-	var accessPoints EposAccessPoints
+// InstallEnvironment installs a new environment, or updates the installed one with the same name, version, platform
+// and context when isEdit is set. Returns the id of the environment.
+func (a *App) InstallEnvironment(platform string, environmentSetup EnvironmentSetup, variables []Section, skipImagesAutoupdate bool, isEdit bool) (string, error) {
+	driver, err := getPlatformDriver(platform)
+	if err != nil {
+		return "", err
+	}
 
-	// TODO run the install script
-	if platform == "docker" {
-		err = a.installDockerEnvironment(environmentSetup, variables, autoUpdateImages, isEdit)
+	// The context only identifies the kubernetes environments
+	environmentSetup.Context = environmentContext(platform, environmentSetup.Context)
+	if err := checkEnvironmentHost(platform, environmentSetup.Host); err != nil {
+		return "", err
+	}
 
-		// Build the access points strings
-		accessPoints = EposAccessPoints{
-			DataPortal: "http://" + os.Getenv("API_HOST_ENV") + ":" + os.Getenv("DATA_PORTAL_PORT"),
-			ApiGateway: "http://" + os.Getenv("API_HOST_ENV") + ":" + os.Getenv("API_PORT") + os.Getenv("DEPLOY_PATH") + os.Getenv("API_PATH") + "/ui/",
+	id := newEnvironmentId()
+	// The variables the environment was installed with, none for a new environment
+	var installedVariables []Section
+	if isEdit {
+		installed, err := a.store.FindEnvironment(environmentSetup.Name, environmentSetup.Version, platform, environmentSetup.Context)
+		if err != nil {
+			return "", err
 		}
-	} else if platform == "kubernetes" {
-		err = a.installKubernetesEnvironment(environmentSetup, variables, autoUpdateImages, isEdit)
-
-		// Build the access points strings
-		accessPoints = EposAccessPoints{
-			DataPortal: os.Getenv("PORTAL_URL_READY"),
-			ApiGateway: os.Getenv("API_URL_READY"),
+		id = installed.ID
+		installedVariables = installed.Variables
+		// The secrets of an environment being edited are received masked, use the saved values
+		variables = copySections(variables)
+		unmaskSecrets(variables, installed.Variables)
+	} else if installed, err := a.store.FindEnvironment(environmentSetup.Name, environmentSetup.Version, platform, environmentSetup.Context); err == nil {
+		// The environments removed outside of the app stay in the database until they are pruned
+		if driver.Status(installed) == environmentMissing {
+			return "", fmt.Errorf("%w: %s %s is missing from the platform, remove the missing environments first", errEnvironmentExists, environmentSetup.Name, environmentSetup.Version)
 		}
-	} else {
-		return fmt.Errorf("unknown platform: %s", platform)
-	}
-	if err != nil {
-		return err
+		return "", fmt.Errorf("%w: %s %s", errEnvironmentExists, environmentSetup.Name, environmentSetup.Version)
 	}
 
-	// Save the environment to the database
-	db, err := sql.Open("sqlite3", databasePath)
+	// Check the variables before starting, the cmds would only fail halfway through the install
+	variableErrors, err := a.ValidateVariables(platform, variables)
 	if err != nil {
-		return err
+		return "", err
 	}
-	defer db.Close()
-
-	// Convert the variables to a JSON string
-	variablesJson, err := json.Marshal(variables)
+	portErrors, err := a.checkPorts(id, platform, variables)
 	if err != nil {
-		return err
+		return "", err
+	}
+	variableErrors = append(variableErrors, portErrors...)
+	if len(variableErrors) > 0 {
+		return "", invalidVariablesError{errors: variableErrors}
 	}
 
-	// TODO: maybe get the ports from the environment variables istead of using the values from the variables variable (the deploy might change them if they are already in use)
-
-	// Upsert the environment into the database
-	_, err = db.Exec("INSERT OR REPLACE INTO environments(name, version, platform, dataPortal, apiGateway, variables, context) VALUES(?, ?, ?, ?, ?, ?, ?)",
-		environmentSetup.Name,
-		environmentSetup.Version,
-		platform,
-		accessPoints.DataPortal,
-		accessPoints.ApiGateway,
-		string(variablesJson),
-		environmentSetup.Context,
-	)
-	if err != nil {
-		return err
+	// In offline mode the images are the ones loaded from a bundle, they can't be updated
+	offline := a.isOfflineMode()
+	if offline {
+		skipImagesAutoupdate = true
+		// The kubernetes images are in a registry the app can't list
+		if platform != "kubernetes" {
+			if missing := missingImages(imagesTool(platform), imagesOfVariables(variables)); len(missing) > 0 {
+				return "", fmt.Errorf("offline mode: the images %s are not loaded, load an image bundle with them first", strings.Join(missing, ", "))
+			}
+		}
 	}
 
-	// Return nil if there was no error
-	return nil
-}
+	// Set the flag for the auto update of the images
+	autoUpdateImages := !skipImagesAutoupdate
+	environment := Environment{
+		ID:               id,
+		Platform:         platform,
+		EnvironmentSetup: environmentSetup,
+		Variables:        variables,
+	}
 
-func (a *App) installDockerEnvironment(environmentSetup EnvironmentSetup, variables []Section, autoUpdateImages bool, isEdit bool) error {
 	// Generate a temporary file with the environment variables
 	envTempFilePath, err := generateTempFile(os.TempDir(), "configurations", variablesToBinary(variables))
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	// Add the arguments to the docker command
-	old := os.Stdout // keep backup of the real stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	// Create a channel to wait for the command to finish
-	done := make(chan error)
-
-	go func() {
-		// Run the docker command
-		err := dockerMethods.CreateEnvironment(
-			envTempFilePath,                     // the file with the environment variables
-			"",                                  // the docker-compose file
-			"",                                  // external ip
-			environmentSetup.Name,               // the name of the environment
-			environmentSetup.Version,            // the version of the environment
-			fmt.Sprintf("%t", isEdit),           // if the environment is being edited/updated
-			fmt.Sprintf("%t", autoUpdateImages), // if the images should be updated
-		)
-
-		// back to normal state
-		w.Close()
-		os.Stdout = old // restoring the real stdout
-		done <- err
-	}()
-
-	// Create a scanner to read the output line by line
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		// Emit the events to the frontend for each line
-		wailsRuntime.EventsEmit(a.ctx, "TERMINAL_OUTPUT", scanner.Text())
+	// Reserve the ports before the install starts, so that another install can't take them
+	if err := a.store.ReservePorts(id, portReservationsOf(platform, variables)); err != nil {
+		os.Remove(envTempFilePath)
+		return "", err
 	}
 
-	err = <-done // wait for the command to finish
+	// Run the install script, it can be cancelled with CancelOperation
+	ctx, op := a.startOperation("install", environment)
+	defer a.endOperation(op)
+	result, err := a.runOperation(ctx, op, driver, workerRequest{
+		Operation:        "install",
+		EnvFilePath:      envTempFilePath,
+		Environment:      environment,
+		AutoUpdateImages: autoUpdateImages,
+		IsEdit:           isEdit,
+		Offline:          offline,
+	})
 
 	//Remove the temporary file
 	os.Remove(envTempFilePath)
 
-	return err
-}
-
-func (a *App) installKubernetesEnvironment(environmentSetup EnvironmentSetup, variables []Section, autoUpdateImages bool, isEdit bool) error {
-	// Generate a temporary file with the environment variables
-	envTempFilePath, err := generateTempFile(os.TempDir(), "configurations", variablesToBinary(variables))
 	if err != nil {
-		return err
+		// The ports go back to those of the installed environment, a new one releases them
+		if reserveErr := a.store.ReservePorts(id, portReservationsOf(platform, installedVariables)); reserveErr != nil {
+			fmt.Fprintln(os.Stderr, "Error releasing the ports:", reserveErr)
+		}
+		return "", err
 	}
 
-	old := os.Stdout // keep backup of the real stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	// Create a channel to wait for the command to finish
-	done := make(chan error)
-
-	go func() {
-		// Run the kubernetes command
-		err := kubernetesMethods.CreateEnvironment(
-			envTempFilePath,                     // the file with the environment variables
-			environmentSetup.Context,            // the context
-			environmentSetup.Name,               // the namespace
-			environmentSetup.Version,            // the version of the environment
-			fmt.Sprintf("%t", autoUpdateImages), // if the images should be updated
-			fmt.Sprintf("%t", isEdit),           // if the environment is being edited/updated
-		)
+	// The access points built by the worker from the variables used by the install
+	environment.AccessPoints = result.AccessPoints
 
-		w.Close()
-		os.Stdout = old // restoring the real stdout
-		done <- err
-	}()
+	// TODO: maybe get the ports from the environment variables istead of using the values from the variables variable (the deploy might change them if they are already in use)
 
-	// Create a scanner to read the output line by line
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		// Emit the events to the frontend for each line
-		wailsRuntime.EventsEmit(a.ctx, "TERMINAL_OUTPUT", scanner.Text())
+	// Save the environment to the store
+	err = a.store.SaveEnvironment(environment)
+	if err != nil {
+		return "", err
 	}
 
-	err = <-done // wait for the command to finish
-
-	//Remove the temporary file
-	os.Remove(envTempFilePath)
+	return id, nil
+}
 
-	return err
+// Run the operation of the driver in a worker process, sending its output to the frontend as TERMINAL_OUTPUT events
+// and the progress parsed from the output as OPERATION_PROGRESS events. The operation and its output are saved in the history.
+func (a *App) runOperation(ctx context.Context, op *operation, driver PlatformDriver, request workerRequest) (workerResult, error) {
+	a.recordOperationStart(op)
+	tracker := newProgressTracker(op, driver, a.emitProgress)
+	output := newOperationOutput(op.ID, a.emit, tracker, newOperationLog(a.store, op.ID))
+	output.secrets = secretValuesOf(request.Environment.Variables)
+	tracker.start()
+
+	result, err := a.runInWorkerWhenFree(ctx, op, driver, output, request)
+
+	output.Close()
+	tracker.finish(err)
+	a.recordOperationEnd(op, err)
+	return result, err
 }
 
-// Convert the variables to a binary to be saved in a file
-func variablesToBinary(variables []Section) []byte {
-	var result []string
-	for _, section := range variables {
-		variables := section.Variables // a map of variable names to values
-		for name, value := range variables {
-			result = append(result, fmt.Sprintf("%s=%s", name, value))
+// Wait for the resources shared with the other operations of the platform to be free, then run the worker
+func (a *App) runInWorkerWhenFree(ctx context.Context, op *operation, driver PlatformDriver, output *operationOutput, request workerRequest) (workerResult, error) {
+	if user, ok := driver.(sharedResourceUser); ok {
+		release, err := a.operations.acquire(ctx, user.SharedResources(op.Kind), func(resource string) {
+			fmt.Fprintf(output, "[WAITING] Another operation is using the %s, waiting for it to finish\n", resource)
+		})
+		if err != nil {
+			return workerResult{}, err
 		}
+		defer release()
 	}
+	return runInWorker(ctx, output, request)
+}
 
-	return []byte(strings.Join(result, "\n"))
+// Send a progress event to the frontend
+func (a *App) emitProgress(event ProgressEvent) {
+	a.emit(progressEventName, event)
 }
 
-// Generate a temporary file with the given data and return the file path
+// Generate a temporary file with the given data and return the file path.
+// The file can hold secrets: it is only readable by the user and must be removed as soon as it is not needed.
 func generateTempFile(dname string, filetype string, text []byte) (string, error) {
-	// If dname already exists, remove it
-	if _, err := os.Stat(dname); err == nil {
-		os.RemoveAll(dname)
-	}
-
 	tmpFile, err := os.CreateTemp(dname, filetype)
 	if err != nil {
 		return "", err
 	}
 	defer tmpFile.Close()
 	name := tmpFile.Name()
-	if _, err = tmpFile.Write(text); err != nil {
+	if err = tmpFile.Chmod(0600); err == nil {
+		_, err = tmpFile.Write(text)
+	}
+	if err != nil {
+		tmpFile.Close()
+		os.Remove(name)
 		return "", err
 	}
 
 	return name, nil
 }
+
+// Generate the id of a new environment
+func newEnvironmentId() string {
+	return newOperationId()
+}
@@ -0,0 +1,44 @@
+package main
+
+// Stop the services of an installed environment to free the resources of the machine, its data is kept.
+// On kubernetes the deployments of the namespace are scaled to zero.
+func (a *App) StopEnvironment(id string) error {
+	return a.runLifecycleOperation(id, "stop", "")
+}
+
+// Start the services of an environment stopped with StopEnvironment
+func (a *App) StartEnvironment(id string) error {
+	return a.runLifecycleOperation(id, "start", "")
+}
+
+// Restart all the services of an installed environment
+func (a *App) RestartEnvironment(id string) error {
+	return a.runLifecycleOperation(id, "restart", "")
+}
+
+// Restart one service of an installed environment, as listed by GetEnvironmentStatus
+func (a *App) RestartService(id, service string) error {
+	return a.runLifecycleOperation(id, "restart", service)
+}
+
+// Run a stop, start or restart on an environment, sending its output to the frontend.
+// It is recorded in the history and can be cancelled with CancelOperation.
+func (a *App) runLifecycleOperation(id, kind, service string) error {
+	environment, err := a.store.GetEnvironment(id)
+	if err != nil {
+		return err
+	}
+	driver, err := getPlatformDriver(environment.Platform)
+	if err != nil {
+		return err
+	}
+
+	ctx, op := a.startOperation(kind, environment)
+	defer a.endOperation(op)
+	_, err = a.runOperation(ctx, op, driver, workerRequest{
+		Operation:   kind,
+		Environment: environment,
+		Service:     service,
+	})
+	return err
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The migrations of the database schema, applied in order of their numeric prefix (e.g. 0002_add_status.sql)
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// Returned when the database has been migrated by a newer version of the application
+type databaseTooNewError struct {
+	databaseVersion int
+	appVersion      int
+}
+
+func (e databaseTooNewError) Error() string {
+	return fmt.Sprintf("the database schema version (%d) is newer than the one supported by this version of the application (%d), please update the application", e.databaseVersion, e.appVersion)
+}
+
+// Load the embedded migrations sorted by version
+func loadMigrations() ([]migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []migration
+	for _, entry := range entries {
+		// The version is the number before the first underscore of the file name
+		prefix, _, found := strings.Cut(entry.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		if !found || err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, migration{
+			version: version,
+			name:    entry.Name(),
+			sql:     string(content),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	// Two migrations with the same version would be applied in a random order
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].version == migrations[i-1].version {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", migrations[i].version, migrations[i-1].name, migrations[i].name)
+		}
+	}
+
+	return migrations, nil
+}
+
+// Bring the schema of the database up to date, backing up the file at dbPath before changing it
+func migrateDatabase(db *sql.DB, dbPath string) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	latestVersion := 0
+	if len(migrations) > 0 {
+		latestVersion = migrations[len(migrations)-1].version
+	}
+
+	// Check if there is an existing database to back up (the connection creates the file lazily)
+	existing := false
+	if info, err := os.Stat(dbPath); err == nil && info.Size() > 0 {
+		existing = true
+	}
+
+	currentVersion, err := getSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	// Never touch a database that was migrated by a newer version of the app
+	if currentVersion > latestVersion {
+		return databaseTooNewError{databaseVersion: currentVersion, appVersion: latestVersion}
+	}
+	if currentVersion == latestVersion {
+		return nil
+	}
+
+	// Back up the database before changing its schema
+	if existing {
+		backupPath := fmt.Sprintf("%s.v%d-%s.bak", dbPath, currentVersion, time.Now().Format("20060102150405"))
+		if err := copyFile(dbPath, backupPath); err != nil {
+			return fmt.Errorf("error backing up the database before migrating it: %w", err)
+		}
+	}
+
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS schema_version (version INTEGER PRIMARY KEY, name TEXT, appliedAt TEXT)")
+	if err != nil {
+		return err
+	}
+
+	// Apply each pending migration in its own transaction
+	for _, m := range migrations {
+		if m.version <= currentVersion {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return fmt.Errorf("error applying migration %s: %w", m.name, err)
+		}
+	}
+
+	return nil
+}
+
+// Get the version of the schema, 0 if the database has never been migrated
+func getSchemaVersion(db *sql.DB) (int, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'").Scan(&count)
+	if err != nil || count == 0 {
+		return 0, err
+	}
+
+	var version int
+	err = db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version)
+	return version, err
+}
+
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op if the transaction has been committed
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.sql); err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO schema_version(version, name, appliedAt) VALUES(?, ?, ?)", m.version, m.name, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Copy the file at src to dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,18 @@
+-- Tables created by the versions of the application before the schema migrations were introduced,
+-- IF NOT EXISTS is kept so that the migration can be applied to those databases
+CREATE TABLE IF NOT EXISTS environments (
+    name TEXT,
+    version TEXT,
+    platform TEXT,
+    context TEXT,
+    dataPortal TEXT,
+    apiGateway TEXT,
+    variables TEXT,
+    PRIMARY KEY (name, version, platform)
+);
+
+CREATE TABLE IF NOT EXISTS platform_paths (
+    platform TEXT,
+    path TEXT,
+    PRIMARY KEY (platform)
+);
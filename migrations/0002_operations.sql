@@ -0,0 +1,23 @@
+-- The history of the installs, populates and deletes of the environments, kept after the environment is deleted
+CREATE TABLE operations (
+    id TEXT PRIMARY KEY,
+    kind TEXT NOT NULL,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL,
+    platform TEXT NOT NULL,
+    context TEXT NOT NULL DEFAULT '',
+    startedAt TEXT NOT NULL,
+    endedAt TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX operations_environment ON operations (name, version, platform, startedAt);
+
+-- The output of the operations, saved in chunks while they run
+CREATE TABLE operation_logs (
+    operationId TEXT NOT NULL REFERENCES operations (id) ON DELETE CASCADE,
+    chunk INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    PRIMARY KEY (operationId, chunk)
+);
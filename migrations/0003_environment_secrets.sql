@@ -0,0 +1,2 @@
+-- The secret variables of the environments, encrypted, their values are emptied in the variables column
+ALTER TABLE environments ADD COLUMN secrets TEXT NOT NULL DEFAULT '';
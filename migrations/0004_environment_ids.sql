@@ -0,0 +1,44 @@
+-- The environments are identified by a generated id instead of their name, version and platform,
+-- and the kubernetes context is part of what makes an environment unique (the same namespace can be on two clusters).
+-- The context is only kept for the kubernetes environments.
+CREATE TABLE environments_new (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL,
+    platform TEXT NOT NULL,
+    context TEXT NOT NULL DEFAULT '',
+    dataPortal TEXT NOT NULL DEFAULT '',
+    apiGateway TEXT NOT NULL DEFAULT '',
+    variables TEXT NOT NULL,
+    secrets TEXT NOT NULL DEFAULT '',
+    UNIQUE (name, version, platform, context)
+);
+
+INSERT INTO environments_new (id, name, version, platform, context, dataPortal, apiGateway, variables, secrets)
+SELECT lower(hex(randomblob(8))),
+       name,
+       version,
+       platform,
+       CASE WHEN platform = 'kubernetes' THEN COALESCE(context, '') ELSE '' END,
+       COALESCE(dataPortal, ''),
+       COALESCE(apiGateway, ''),
+       COALESCE(variables, '[]'),
+       secrets
+FROM environments;
+
+DROP TABLE environments;
+ALTER TABLE environments_new RENAME TO environments;
+
+-- The operations keep the name, version and platform to be listed after the environment is deleted
+ALTER TABLE operations ADD COLUMN environmentId TEXT NOT NULL DEFAULT '';
+
+UPDATE operations
+SET environmentId = COALESCE((SELECT environments.id
+                              FROM environments
+                              WHERE environments.name = operations.name
+                                AND environments.version = operations.version
+                                AND environments.platform = operations.platform
+                                AND environments.context = CASE WHEN operations.platform = 'kubernetes' THEN operations.context ELSE '' END), '');
+
+DROP INDEX operations_environment;
+CREATE INDEX operations_environment ON operations (environmentId, startedAt);
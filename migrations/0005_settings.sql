@@ -0,0 +1,5 @@
+-- The settings of the app that are not tied to an environment (e.g. the offline mode), by key
+CREATE TABLE settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
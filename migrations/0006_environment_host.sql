@@ -0,0 +1,2 @@
+-- The address of the machine in the access points of the docker and podman environments, the default route if empty
+ALTER TABLE environments ADD COLUMN host TEXT NOT NULL DEFAULT '';
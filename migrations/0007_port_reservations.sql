@@ -0,0 +1,18 @@
+-- The ports of the machine reserved by the environments, a port can only be reserved by one environment
+CREATE TABLE port_reservations (
+    port INTEGER PRIMARY KEY,
+    environmentId TEXT NOT NULL,
+    variable TEXT NOT NULL
+);
+
+CREATE INDEX port_reservations_environment ON port_reservations (environmentId);
+
+-- Reserve the ports published by the docker and podman environments already installed
+INSERT OR IGNORE INTO port_reservations (port, environmentId, variable)
+SELECT CAST(variable.value AS INTEGER), environments.id, variable.key
+FROM environments,
+     json_each(environments.variables) AS section,
+     json_each(section.value, '$.variables') AS variable
+WHERE environments.platform != 'kubernetes'
+  AND variable.key IN ('DATA_PORTAL_PORT', 'API_PORT')
+  AND CAST(variable.value AS INTEGER) BETWEEN 1 AND 65535;
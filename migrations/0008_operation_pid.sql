@@ -0,0 +1,3 @@
+-- The process that runs the operation, the running operations of a process that is gone were interrupted.
+-- The operations saved before are from processes that are gone.
+ALTER TABLE operations ADD COLUMN pid INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The tables of the versions of the app before the schema migrations, with a docker environment
+const baselineSchema = `
+CREATE TABLE environments (name TEXT, version TEXT, platform TEXT, context TEXT, dataPortal TEXT, apiGateway TEXT, variables TEXT, PRIMARY KEY (name, version, platform));
+CREATE TABLE platform_paths (platform TEXT, path TEXT, PRIMARY KEY (platform));
+INSERT INTO environments VALUES ('env1', '1.0', 'docker', 'ignored', 'http://host:32000', 'http://host:33000/api/v1/ui/',
+	'[{"name":"Ports","variables":{"API_PORT":"33000","DATA_PORTAL_PORT":"32000","POSTGRESQL_PASSWORD":"changeme"}}]');
+`
+
+func TestMigrateDatabase(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := migrations[len(migrations)-1].version
+
+	tests := []struct {
+		name         string
+		setup        string
+		environments int
+		reservations int
+		backup       bool
+		tooNew       bool
+	}{
+		{name: "new database"},
+		{name: "baseline database", setup: baselineSchema, environments: 1, reservations: 2, backup: true},
+		{name: "database of a newer app", setup: "CREATE TABLE schema_version (version INTEGER PRIMARY KEY, name TEXT, appliedAt TEXT); INSERT INTO schema_version VALUES (9999, 'future', '');", tooNew: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dbPath := filepath.Join(dir, "environments.db")
+			if test.setup != "" {
+				db, err := sql.Open("sqlite3", dbPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := db.Exec(test.setup); err != nil {
+					t.Fatal(err)
+				}
+				db.Close()
+			}
+
+			store, err := newSQLiteEnvironmentStore(dbPath)
+			if test.tooNew {
+				var tooNew databaseTooNewError
+				if !errors.As(err, &tooNew) {
+					t.Fatalf("expected databaseTooNewError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+
+			version, err := getSchemaVersion(store.db)
+			if err != nil || version != latest {
+				t.Errorf("schema version = %d, %v, want %d", version, err, latest)
+			}
+
+			environments, err := store.GetEnvironments()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(environments) != test.environments {
+				t.Fatalf("got %d environments, want %d", len(environments), test.environments)
+			}
+			for _, environment := range environments {
+				if environment.ID == "" {
+					t.Error("the environment has no id")
+				}
+				// The context is only kept for kubernetes and the secrets are encrypted but still readable
+				if environment.EnvironmentSetup.Context != "" {
+					t.Errorf("context = %q, want it empty for docker", environment.EnvironmentSetup.Context)
+				}
+				if value := environment.Variables[0].Variables["POSTGRESQL_PASSWORD"]; value != "changeme" {
+					t.Errorf("POSTGRESQL_PASSWORD = %q, want changeme", value)
+				}
+			}
+
+			reservations, err := store.GetPortReservations()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(reservations) != test.reservations {
+				t.Errorf("got %d port reservations, want %d", len(reservations), test.reservations)
+			}
+
+			backups, _ := filepath.Glob(dbPath + ".v0-*.bak")
+			if (len(backups) > 0) != test.backup {
+				t.Errorf("backups = %v, want a backup: %t", backups, test.backup)
+			}
+		})
+	}
+}
+
+// Applying the migrations again to an up to date database changes nothing
+func TestMigrateDatabaseTwice(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "environments.db")
+	if err := os.WriteFile(dbPath, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		store, err := newSQLiteEnvironmentStore(dbPath)
+		if err != nil {
+			t.Fatalf("open %d: %v", i, err)
+		}
+		store.Close()
+	}
+	if backups, _ := filepath.Glob(dbPath + ".v*.bak"); len(backups) != 0 {
+		t.Errorf("an up to date database was backed up: %v", backups)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+
+	"golang.org/x/net/http/httpproxy"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// The setting with the network settings as json, none if empty
+const networkSettingsSetting = "network"
+
+// NetworkSettings are the proxy and the certificates used by the requests of the app, the proxy is also given to the
+// commands it runs (docker, kubectl, ...)
+type NetworkSettings struct {
+	// The proxy of the http and https requests (e.g. http://proxy.example.org:3128).
+	// If empty the one of the environment is used (HTTP_PROXY, HTTPS_PROXY and NO_PROXY).
+	ProxyURL string `json:"proxyUrl"`
+	// The hosts reached without the proxy, separated by commas (e.g. .example.org,10.0.0.0/8).
+	// localhost and the loopback addresses never use the proxy.
+	NoProxy string `json:"noProxy"`
+	// A PEM file with the certificate authorities to trust in addition to those of the system
+	// (e.g. the one of a proxy inspecting the https traffic)
+	CACertificatesPath string `json:"caCertificatesPath"`
+}
+
+// The variables of the proxy read by the commands, both cases are used
+var proxyEnvironmentVariables = []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy"}
+
+// The values of the proxy variables the app was started with, restored when the proxy setting is removed
+var originalProxyEnvironment = func() map[string]*string {
+	values := map[string]*string{}
+	for _, name := range proxyEnvironmentVariables {
+		if value, ok := os.LookupEnv(name); ok {
+			values[name] = &value
+		} else {
+			values[name] = nil
+		}
+	}
+	return values
+}()
+
+// The transport of the requests when there are no network settings, the others are built from it
+var defaultNetworkTransport = http.DefaultTransport.(*http.Transport)
+
+// The settings in use and the transport of the requests built from them
+var network struct {
+	mu        sync.Mutex
+	settings  NetworkSettings
+	transport http.RoundTripper
+}
+
+// Get the proxy and the certificates used by the requests of the app
+func (a *App) GetNetworkSettings() (NetworkSettings, error) {
+	value, err := a.store.GetSetting(networkSettingsSetting)
+	if err != nil || value == "" {
+		return NetworkSettings{}, err
+	}
+
+	var settings NetworkSettings
+	if err := json.Unmarshal([]byte(value), &settings); err != nil {
+		return NetworkSettings{}, fmt.Errorf("error reading the network settings: %w", err)
+	}
+	return settings, nil
+}
+
+// Save the proxy and the certificates and use them for the next requests and commands
+func (a *App) SetNetworkSettings(settings NetworkSettings) error {
+	// Check the settings before saving them
+	if _, err := settings.newTransport(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if err := a.store.SetSetting(networkSettingsSetting, string(data)); err != nil {
+		return err
+	}
+	return applyNetworkSettings(settings)
+}
+
+// Ask for the PEM file with the certificate authorities, returns an empty path if the dialog was cancelled
+func (a *App) OpenCACertificatesDialog() (string, error) {
+	return wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title:   "Select the certificate authorities",
+		Filters: []wailsRuntime.FileFilter{{DisplayName: "Certificates (*.pem, *.crt)", Pattern: "*.pem;*.crt"}},
+	})
+}
+
+// Use the saved network settings, done when the app starts
+func (a *App) loadNetworkSettings() {
+	settings, err := a.GetNetworkSettings()
+	if err == nil {
+		err = applyNetworkSettings(settings)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error applying the network settings:", err)
+	}
+}
+
+// Use the settings for the next requests of the app and set the proxy in the environment of the commands it runs
+func applyNetworkSettings(settings NetworkSettings) error {
+	transport, err := settings.newTransport()
+	if err != nil {
+		return err
+	}
+
+	network.mu.Lock()
+	network.settings = settings
+	network.transport = transport
+	network.mu.Unlock()
+
+	for _, name := range proxyEnvironmentVariables {
+		value := originalProxyEnvironment[name]
+		switch {
+		case settings.ProxyURL != "" && (name == "NO_PROXY" || name == "no_proxy"):
+			os.Setenv(name, settings.NoProxy)
+		case settings.ProxyURL != "":
+			os.Setenv(name, settings.ProxyURL)
+		case value != nil:
+			os.Setenv(name, *value)
+		default:
+			os.Unsetenv(name)
+		}
+	}
+	return nil
+}
+
+// The network settings in use, given to the worker of the operations
+func currentNetworkSettings() NetworkSettings {
+	network.mu.Lock()
+	defer network.mu.Unlock()
+	return network.settings
+}
+
+// The transport of the requests of the app, with the proxy and the certificates of the settings
+func networkTransport() http.RoundTripper {
+	network.mu.Lock()
+	defer network.mu.Unlock()
+	if network.transport == nil {
+		return defaultNetworkTransport
+	}
+	return network.transport
+}
+
+// A client for the requests of the app, with the proxy and the certificates of the settings
+func networkHTTPClient() *http.Client {
+	return &http.Client{Transport: networkTransport()}
+}
+
+// Build the transport of the requests from the settings, an error is returned if they are not valid
+func (s NetworkSettings) newTransport() (*http.Transport, error) {
+	transport := defaultNetworkTransport.Clone()
+
+	if s.ProxyURL != "" {
+		proxy, err := url.Parse(s.ProxyURL)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("the proxy must be a url like http://proxy.example.org:3128: %s", s.ProxyURL)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("the proxy must be an http, https or socks5 url: %s", s.ProxyURL)
+		}
+		proxyFunc := (&httpproxy.Config{HTTPProxy: s.ProxyURL, HTTPSProxy: s.ProxyURL, NoProxy: s.NoProxy}).ProxyFunc()
+		transport.Proxy = func(request *http.Request) (*url.URL, error) {
+			return proxyFunc(request.URL)
+		}
+	} else if s.NoProxy != "" {
+		return nil, errors.New("the hosts reached without the proxy are only used with a proxy")
+	}
+
+	if s.CACertificatesPath != "" {
+		pem, err := os.ReadFile(s.CACertificatesPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the certificate authorities: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			// The system certificates can't be read on some systems (e.g. old windows versions)
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificate found in %s", s.CACertificatesPath)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return transport, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The log of an operation is saved when this much time passed since the last save, or when it grows over operationLogMaxBuffer
+const (
+	operationLogFlushInterval = 2 * time.Second
+	operationLogMaxBuffer     = 64 * 1024
+)
+
+// Saves the output of an operation in the store. The lines are saved in chunks, not to write to the database
+// for every line of a docker pull, but often enough to keep most of the log if the app is closed.
+type operationLog struct {
+	mu        sync.Mutex
+	store     EnvironmentStore
+	id        string
+	buffer    strings.Builder
+	lastFlush time.Time
+}
+
+func newOperationLog(store EnvironmentStore, id string) *operationLog {
+	return &operationLog{
+		store:     store,
+		id:        id,
+		lastFlush: time.Now(),
+	}
+}
+
+// Add a line of output to the log
+func (l *operationLog) line(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buffer.WriteString(line)
+	l.buffer.WriteString("\n")
+	if time.Since(l.lastFlush) >= operationLogFlushInterval || l.buffer.Len() >= operationLogMaxBuffer {
+		l.flushLocked()
+	}
+}
+
+// Save the lines that are not saved yet
+func (l *operationLog) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.flushLocked()
+}
+
+func (l *operationLog) flushLocked() {
+	l.lastFlush = time.Now()
+	if l.buffer.Len() == 0 {
+		return
+	}
+	// The history is not worth failing the operation for
+	if err := l.store.AppendOperationLog(l.id, l.buffer.String()); err != nil {
+		fmt.Fprintln(os.Stderr, "Error saving the log of the operation", l.id, err)
+	}
+	l.buffer.Reset()
+}
+
+// Add the operation to the history of its environment, with the running status
+func (a *App) recordOperationStart(op *operation) {
+	err := a.store.AddOperation(OperationRecord{
+		ID:            op.ID,
+		Kind:          op.Kind,
+		EnvironmentID: op.EnvironmentID,
+		Platform:      op.Platform,
+		Name:          op.Name,
+		Version:       op.Version,
+		Context:       op.Context,
+		StartedAt:     op.StartedAt,
+		Status:        operationRunning,
+		PID:           os.Getpid(),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error saving the operation", op.ID, err)
+	}
+}
+
+// Save the status of the operation given the error it returned
+func (a *App) recordOperationEnd(op *operation, opErr error) {
+	status := operationSucceeded
+	errorMessage := ""
+	if errors.Is(opErr, context.Canceled) {
+		status = operationCancelled
+	} else if opErr != nil {
+		status = operationFailed
+		errorMessage = opErr.Error()
+	}
+
+	if err := a.store.FinishOperation(op.ID, time.Now(), status, errorMessage); err != nil {
+		fmt.Fprintln(os.Stderr, "Error saving the end of the operation", op.ID, err)
+	}
+}
+
+// Get the installs, populates and deletes of an environment, the most recent first
+func (a *App) GetOperationHistory(environmentId string) ([]OperationRecord, error) {
+	return a.store.GetOperations(environmentId)
+}
+
+// Get the last operations of all the environments, with those of the deleted environments and of the failed installs
+// of new environments, the most recent first
+func (a *App) GetRecentOperations(limit int) ([]OperationRecord, error) {
+	return a.store.GetRecentOperations(limit)
+}
+
+// Get the output of an operation of the history
+func (a *App) GetOperationLog(id string) (string, error) {
+	return a.store.GetOperationLog(id)
+}
+
+// If the process running an operation of the history is alive and is not this one. A process with the id of this one
+// ran the operations before this one started, ids are reused.
+func isOtherProcessAlive(pid int) bool {
+	return pid != os.Getpid() && isProcessAlive(pid)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// The event sent to the frontend for each line printed by an operation, with the line and the id of the operation
+const terminalOutputEventName = "TERMINAL_OUTPUT"
+
+// The output of one operation: the lines written to it are sent to the frontend as TERMINAL_OUTPUT events,
+// given to the progress tracker of the operation and saved in its log
+type operationOutput struct {
+	mu          sync.Mutex
+	operationId string
+	emit        func(eventName string, data ...interface{})
+	tracker     *progressTracker
+	log         *operationLog
+	// The values replaced by the mask before the lines are sent
+	secrets []string
+	// The last line, until its newline is written
+	partial bytes.Buffer
+}
+
+// Create the output of the operation with the given id, the tracker and the log can be nil
+func newOperationOutput(operationId string, emit func(eventName string, data ...interface{}), tracker *progressTracker, log *operationLog) *operationOutput {
+	return &operationOutput{
+		operationId: operationId,
+		emit:        emit,
+		tracker:     tracker,
+		log:         log,
+	}
+}
+
+// Write sends every complete line of p, the rest is kept until the next write
+func (o *operationOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.partial.Write(p)
+	for {
+		i := bytes.IndexByte(o.partial.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := string(o.partial.Next(i + 1))
+		o.send(strings.TrimRight(line, "\r\n"))
+	}
+	return len(p), nil
+}
+
+// Close sends the last line if it didn't end with a newline and saves the rest of the log
+func (o *operationOutput) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.partial.Len() > 0 {
+		o.send(strings.TrimRight(o.partial.String(), "\r"))
+		o.partial.Reset()
+	}
+	if o.log != nil {
+		o.log.flush()
+	}
+	return nil
+}
+
+func (o *operationOutput) send(line string) {
+	line = redactSecrets(line, o.secrets)
+	o.emit(terminalOutputEventName, line, o.operationId)
+	if o.tracker != nil {
+		o.tracker.line(line)
+	}
+	if o.log != nil {
+		o.log.line(line)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"time"
+)
+
+// The hidden command that runs one operation of a platform driver in a child process of the app.
+// The docker and kubernetes cmds print to os.Stdout and load the variables in the process environment,
+// so every operation gets its own process to have its own output and to run in parallel with the others.
+const operationWorkerCommand = "__operation-worker"
+
+// The time given to the output of the worker to be closed after it exits, e.g. by a command it left running
+const operationWorkerWaitDelay = 5 * time.Second
+
+// The operation to run, sent as json to the stdin of the worker
+type workerRequest struct {
+	// install, populate, delete, stop, start, restart, export-images or load-images
+	Operation        string      `json:"operation"`
+	EnvFilePath      string      `json:"envFilePath"`
+	Environment      Environment `json:"environment"`
+	Path             string      `json:"path,omitempty"`
+	AutoUpdateImages bool        `json:"autoUpdateImages,omitempty"`
+	IsEdit           bool        `json:"isEdit,omitempty"`
+	// The service to restart, all of them if empty
+	Service string `json:"service,omitempty"`
+	// The images to save in the bundle at Path
+	Images []string `json:"images,omitempty"`
+	// The registry where the loaded images are pushed, none if empty
+	Registry string `json:"registry,omitempty"`
+	// The images are loaded from a bundle, the cmds must not look them up on Docker Hub
+	Offline bool `json:"offline,omitempty"`
+	// The proxy and the certificates of the app, used by the requests of the cmds and the commands they run
+	Network NetworkSettings `json:"network"`
+	// The file where the worker writes its result
+	ResultPath string `json:"resultPath"`
+}
+
+// The result of the operation, written as json by the worker
+type workerResult struct {
+	AccessPoints EposAccessPoints `json:"accessPoints"`
+	Error        string           `json:"error,omitempty"`
+	Cancelled    bool             `json:"cancelled,omitempty"`
+}
+
+// Run the operation in a worker process writing its output to out.
+// When ctx is done the stdin of the worker is closed, it then cancels the operation and cleans up before exiting.
+func runInWorker(ctx context.Context, out io.Writer, request workerRequest) (workerResult, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return workerResult{}, err
+	}
+
+	resultFile, err := os.CreateTemp("", "epos-operation-result-")
+	if err != nil {
+		return workerResult{}, err
+	}
+	resultFile.Close()
+	defer os.Remove(resultFile.Name())
+	request.ResultPath = resultFile.Name()
+	request.Network = currentNetworkSettings()
+
+	cmd := exec.Command(executable, operationWorkerCommand)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.WaitDelay = operationWorkerWaitDelay
+	hideConsoleWindow(cmd)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return workerResult{}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return workerResult{}, err
+	}
+
+	// Send the request, stdin is then kept open until the operation is over or cancelled
+	if err := json.NewEncoder(stdin).Encode(request); err != nil {
+		stdin.Close()
+		cmd.Wait()
+		return workerResult{}, err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		stdin.Close()
+	})
+	waitErr := cmd.Wait()
+	stop()
+	stdin.Close()
+
+	// The worker always writes the result, unless it crashed
+	var result workerResult
+	data, err := os.ReadFile(resultFile.Name())
+	if err != nil || len(data) == 0 || json.Unmarshal(data, &result) != nil {
+		if waitErr == nil {
+			waitErr = errors.New("no result")
+		}
+		return workerResult{}, fmt.Errorf("the %s stopped unexpectedly: %w", request.Operation, waitErr)
+	}
+
+	if result.Cancelled {
+		return result, context.Canceled
+	}
+	if result.Error != "" {
+		return result, errors.New(result.Error)
+	}
+	return result, nil
+}
+
+// The main function of the worker process: read the request from stdin, run it and write the result.
+// Returns the exit code of the process.
+func runOperationWorker() int {
+	var request workerRequest
+	if err := json.NewDecoder(os.Stdin).Decode(&request); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading the operation: %v\n", err)
+		return exitError
+	}
+
+	// The app closes stdin to cancel the operation
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		io.Copy(io.Discard, os.Stdin)
+		cancel()
+	}()
+	// Ctrl+C in a terminal is sent to the worker too, the headless CLI cancels it through stdin
+	// so that it can clean up before exiting
+	signal.Ignore(os.Interrupt)
+
+	result := runWorkerRequest(ctx, request)
+
+	data, err := json.Marshal(result)
+	if err == nil {
+		err = os.WriteFile(request.ResultPath, data, 0600)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing the result of the operation: %v\n", err)
+		return exitError
+	}
+	if result.Error != "" {
+		return exitError
+	}
+	return exitOK
+}
+
+// Run the operation of the request with its platform driver
+func runWorkerRequest(ctx context.Context, request workerRequest) (result workerResult) {
+	// A panic of a cmd is reported as an error of the operation
+	defer func() {
+		if r := recover(); r != nil {
+			result.Error = fmt.Sprintf("unexpected error: %v", r)
+			result.Cancelled = false
+		}
+	}()
+
+	if err := applyNetworkSettings(request.Network); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	// The cmds use the default client and transport of net/http
+	http.DefaultTransport = networkTransport()
+	if request.Offline {
+		http.DefaultClient.Transport = offlineImageTagsTransport{next: http.DefaultTransport}
+	}
+
+	driver, err := getPlatformDriver(request.Environment.Platform)
+	if err == nil {
+		environment := request.Environment
+		switch request.Operation {
+		case "install":
+			err = driver.Install(ctx, request.EnvFilePath, environment, request.AutoUpdateImages, request.IsEdit)
+			if err == nil {
+				result.AccessPoints = driver.AccessPoints(environment)
+			}
+		case "populate":
+			err = driver.Populate(ctx, request.EnvFilePath, request.Path, environment)
+		case "delete":
+			err = driver.Delete(ctx, request.EnvFilePath, environment)
+		case "stop":
+			err = driver.Stop(ctx, environment)
+		case "start":
+			err = driver.Start(ctx, environment)
+		case "restart":
+			err = driver.Restart(ctx, environment, request.Service)
+		case "export-images":
+			err = saveImages(ctx, imagesTool(driver.Name()), request.Images, request.Path)
+		case "load-images":
+			err = loadImages(ctx, imagesTool(driver.Name()), request.Path, request.Registry)
+		default:
+			err = fmt.Errorf("unknown operation: %s", request.Operation)
+		}
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+		result.Cancelled = ctx.Err() != nil
+	}
+	return result
+}
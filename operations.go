@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The event sent to the frontend when an operation starts, with the id to use to cancel it
+const operationStartedEventName = "OPERATION_STARTED"
+
+// An install, populate or delete that is running
+type operation struct {
+	RunningOperation
+	cancel context.CancelFunc
+}
+
+// RunningOperation describes an operation to the frontend
+type RunningOperation struct {
+	ID            string    `json:"id"`
+	Kind          string    `json:"kind"`
+	EnvironmentID string    `json:"environmentId"`
+	Platform      string    `json:"platform"`
+	Name          string    `json:"name"`
+	Version       string    `json:"version"`
+	Context       string    `json:"context"`
+	StartedAt     time.Time `json:"startedAt"`
+}
+
+// The operations that are running, by id
+type operationRegistry struct {
+	mu         sync.Mutex
+	operations map[string]*operation
+	// The shared resources in use, see sharedResourceUser
+	resources map[string]chan struct{}
+}
+
+// Implemented by the drivers whose cmds use something shared by all the environments of the platform
+// (e.g. a container with a fixed name), the operations using the same resource are run one at a time
+type sharedResourceUser interface {
+	// The resources used by the operation, if any
+	SharedResources(operation string) []string
+}
+
+// Register a new operation on the environment and return its context, cancelled by CancelOperation or when the app closes.
+// endOperation must be called when the operation is over.
+func (a *App) startOperation(kind string, environment Environment) (context.Context, *operation) {
+	ctx, cancel := context.WithCancel(a.ctx)
+	op := &operation{
+		RunningOperation: RunningOperation{
+			ID:            newOperationId(),
+			Kind:          kind,
+			EnvironmentID: environment.ID,
+			Platform:      environment.Platform,
+			Name:          environment.EnvironmentSetup.Name,
+			Version:       environment.EnvironmentSetup.Version,
+			Context:       environment.EnvironmentSetup.Context,
+			StartedAt:     time.Now(),
+		},
+		cancel: cancel,
+	}
+
+	a.operations.mu.Lock()
+	if a.operations.operations == nil {
+		a.operations.operations = make(map[string]*operation)
+	}
+	a.operations.operations[op.ID] = op
+	a.operations.mu.Unlock()
+
+	a.emit(operationStartedEventName, op.RunningOperation)
+	return ctx, op
+}
+
+// Remove the operation from the running ones
+func (a *App) endOperation(op *operation) {
+	a.operations.mu.Lock()
+	delete(a.operations.operations, op.ID)
+	a.operations.mu.Unlock()
+
+	// Release the resources of the context
+	op.cancel()
+}
+
+// Cancel a running install, populate or delete given its id
+func (a *App) CancelOperation(id string) error {
+	a.operations.mu.Lock()
+	op, ok := a.operations.operations[id]
+	a.operations.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("operation not found: %s", id)
+	}
+
+	op.cancel()
+	return nil
+}
+
+// Get the operations that are running, oldest first
+func (a *App) GetRunningOperations() []RunningOperation {
+	a.operations.mu.Lock()
+	defer a.operations.mu.Unlock()
+
+	running := []RunningOperation{}
+	for _, op := range a.operations.operations {
+		running = append(running, op.RunningOperation)
+	}
+	sort.Slice(running, func(i, j int) bool {
+		return running[i].StartedAt.Before(running[j].StartedAt)
+	})
+	return running
+}
+
+// Wait until no other operation uses the resources, or until ctx is done. The returned function releases them.
+// waiting is called before blocking on a resource used by another operation.
+func (r *operationRegistry) acquire(ctx context.Context, resources []string, waiting func(resource string)) (func(), error) {
+	// Always take the locks in the same order, so that two operations can't wait for each other
+	resources = append([]string{}, resources...)
+	sort.Strings(resources)
+
+	var locks []chan struct{}
+	release := func() {
+		for _, lock := range locks {
+			<-lock
+		}
+	}
+	for _, resource := range resources {
+		r.mu.Lock()
+		if r.resources == nil {
+			r.resources = make(map[string]chan struct{})
+		}
+		lock, ok := r.resources[resource]
+		if !ok {
+			lock = make(chan struct{}, 1)
+			r.resources[resource] = lock
+		}
+		r.mu.Unlock()
+
+		select {
+		case lock <- struct{}{}:
+			locks = append(locks, lock)
+			continue
+		default:
+			waiting(resource)
+		}
+		select {
+		case lock <- struct{}{}:
+			locks = append(locks, lock)
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// Generate a random id for an operation
+func newOperationId() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// Fall back to the time, ids only need to be unique among the running operations
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Run fn, a call to the docker or kubernetes cmd that doesn't support cancellation, and kill the commands it
+// starts once ctx is done so that it returns as soon as possible. Returns the error of ctx if it was cancelled.
+func runCancellable(ctx context.Context, fn func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	// The cmd may start other commands after the killed one fails (or after a sleep), so keep killing them until it returns.
+	// Each operation runs in its own worker process, so all the children are the ones of the cmd.
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		killChildProcesses()
+		select {
+		case <-done:
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Wait for the duration or until ctx is done, returns the error of ctx if it was cancelled
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
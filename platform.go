@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+)
+
+// PlatformDriver installs and manages the EPOS environments on one installation platform (docker, kubernetes, ...)
+type PlatformDriver interface {
+	// Name of the platform as stored in the environments table
+	Name() string
+	// Check that the tools needed by the platform are installed and ready, returns an error describing what is missing
+	Prerequisites() error
+	// The default variables of the platform, read from its embedded env file
+	DefaultVariables() ([]Section, error)
+	// Install (or update if isEdit is true) the environment using the variables in envFilePath.
+	// If ctx is cancelled, a new environment is removed from the platform.
+	Install(ctx context.Context, envFilePath string, environment Environment, autoUpdateImages bool, isEdit bool) error
+	// The access points of the environment that was just installed
+	AccessPoints(environment Environment) EposAccessPoints
+	// Populate the environment with the metadata files in the folder at path
+	Populate(ctx context.Context, envFilePath string, path string, environment Environment) error
+	// Delete the environment from the platform
+	Delete(ctx context.Context, envFilePath string, environment Environment) error
+	// The status of the environment on the platform: running, stopped, missing, unreachable or unknown
+	Status(environment Environment) string
+	// The containers or pods of the environment with their state, sorted by service
+	Services(environment Environment) ([]ServiceStatus, error)
+	// Stop the services of the environment, keeping their data
+	Stop(ctx context.Context, environment Environment) error
+	// Start the services of an environment that was stopped
+	Start(ctx context.Context, environment Environment) error
+	// Restart one service of the environment, or all of them if service is empty
+	Restart(ctx context.Context, environment Environment, service string) error
+	// The command printing the logs of a service of the environment, killed when ctx is done.
+	// With follow it keeps printing the new lines, tail limits the lines already written (all of them if 0).
+	LogsCommand(ctx context.Context, environment Environment, service string, follow bool, tail int) (*exec.Cmd, error)
+}
+
+// The drivers of the supported platforms, registered in the init function of each driver
+var platformDrivers = map[string]PlatformDriver{}
+
+// Make a platform available to the app
+func registerPlatformDriver(driver PlatformDriver) {
+	if _, ok := platformDrivers[driver.Name()]; ok {
+		panic("platform driver registered twice: " + driver.Name())
+	}
+	platformDrivers[driver.Name()] = driver
+}
+
+// Get the driver for the given platform
+func getPlatformDriver(platform string) (PlatformDriver, error) {
+	driver, ok := platformDrivers[platform]
+	if !ok {
+		return nil, fmt.Errorf("unknown platform: %s", platform)
+	}
+	return driver, nil
+}
+
+// Get the names of the supported platforms in alphabetical order
+func getPlatformNames() []string {
+	var names []string
+	for name := range platformDrivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
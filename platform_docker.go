@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	dockerMethods "github.com/epos-eu/opensource-docker/cmd/methods"
+)
+
+func init() {
+	registerPlatformDriver(dockerDriver{})
+}
+
+// PlatformDriver installing the environments with docker compose
+type dockerDriver struct{}
+
+func (dockerDriver) Name() string {
+	return "docker"
+}
+
+func (dockerDriver) Prerequisites() error {
+	if !isDockerComposeInstalled() {
+		return fmt.Errorf("docker compose is not installed")
+	}
+	if !isDockerRunning() {
+		return fmt.Errorf("docker is not running")
+	}
+	return nil
+}
+
+func (dockerDriver) DefaultVariables() ([]Section, error) {
+	// Read the env file used by the docker cmd
+	return readEnvFile(dockerMethods.GetConfigurationsEmbed())
+}
+
+func (d dockerDriver) Install(ctx context.Context, envFilePath string, environment Environment, autoUpdateImages bool, isEdit bool) error {
+	// The address is given to the cmd, it would otherwise look for the one of the default route and crash without one
+	address, err := environmentAddress(environment)
+	if err != nil {
+		return err
+	}
+	os.Setenv("API_HOST_ENV", address)
+	// The compose file of the cmd is used unless the ports are only published on the loopback
+	composeFilePath := ""
+	if loopbackBindAddress(environment.EnvironmentSetup.Host) != "" {
+		path, err := generateTempFile(os.TempDir(), "dockercompose", environmentComposeFile(environment))
+		if err != nil {
+			return err
+		}
+		defer os.Remove(path)
+		composeFilePath = path
+	}
+
+	err = runCancellable(ctx, func() error {
+		return dockerMethods.CreateEnvironment(
+			envFilePath,                          // the file with the environment variables
+			composeFilePath,                      // the docker-compose file
+			address,                              // external ip
+			environment.EnvironmentSetup.Name,    // the name of the environment
+			environment.EnvironmentSetup.Version, // the version of the environment
+			fmt.Sprintf("%t", isEdit),            // if the environment is being edited/updated
+			fmt.Sprintf("%t", autoUpdateImages),  // if the images should be updated
+		)
+	})
+
+	// Remove the containers created before the cancellation, an environment being edited is left as it is
+	if ctx.Err() != nil && !isEdit {
+		dockerMethods.PrintTask("Installation cancelled, removing the created containers")
+		d.Delete(context.Background(), envFilePath, environment)
+	}
+	return err
+}
+
+func (dockerDriver) AccessPoints(environment Environment) EposAccessPoints {
+	// The docker cmd exports the values it used as environment variables
+	return EposAccessPoints{
+		DataPortal: "http://" + os.Getenv("API_HOST_ENV") + ":" + os.Getenv("DATA_PORTAL_PORT"),
+		ApiGateway: "http://" + os.Getenv("API_HOST_ENV") + ":" + os.Getenv("API_PORT") + os.Getenv("DEPLOY_PATH") + os.Getenv("API_PATH") + "/ui/",
+	}
+}
+
+func (dockerDriver) Populate(ctx context.Context, envFilePath string, path string, environment Environment) error {
+	if err := checkDefaultRoute(); err != nil {
+		return err
+	}
+	useEnvironmentHost(environment)
+	err := runCancellable(ctx, func() error {
+		return dockerMethods.PopulateEnvironment(
+			envFilePath,                          // environment variables file path
+			path,                                 // path to the environment
+			environment.EnvironmentSetup.Name,    // environment name
+			environment.EnvironmentSetup.Version, // environment tag
+		)
+	})
+
+	if ctx.Err() != nil {
+		removeMetadataCacheContainer()
+	}
+	return err
+}
+
+func (dockerDriver) Delete(ctx context.Context, envFilePath string, environment Environment) error {
+	if err := checkDefaultRoute(); err != nil {
+		return err
+	}
+	return runCancellable(ctx, func() error {
+		return dockerMethods.DeleteEnvironment(
+			envFilePath,                          // environment variables file path
+			"",                                   // docker compose file path
+			environment.EnvironmentSetup.Name,    // environment name
+			environment.EnvironmentSetup.Version, // environment version
+		)
+	})
+}
+
+// The docker cmd serves the files to populate with a container named tmc
+func (dockerDriver) SharedResources(operation string) []string {
+	if operation == "populate" {
+		return []string{metadataCacheResource}
+	}
+	return nil
+}
+
+// The number of [TASK] lines printed by the docker cmd
+func (dockerDriver) ProgressSteps(operation string) int {
+	if operation == "install" {
+		return 4
+	}
+	// The populate prints a task for each file
+	return 0
+}
+
+func (dockerDriver) Status(environment Environment) string {
+	return containersStatus("docker", environment)
+}
+
+// Get the status of the containers of an environment from the ps command of docker or podman
+func containersStatus(executable string, environment Environment) string {
+	if _, err := exec.LookPath(executable); err != nil {
+		return environmentUnknown
+	}
+	output, err := RunCommand(exec.Command(executable, "ps", "-a", "--format", "{{.Names}}\t{{.State}}"))
+	if err != nil {
+		// The daemon (or the podman machine) is not running
+		return environmentUnreachable
+	}
+
+	status := environmentMissing
+	for _, line := range strings.Split(output, "\n") {
+		name, state, _ := strings.Cut(strings.TrimSpace(line), "\t")
+		if !isEnvironmentContainer(name, environment) {
+			continue
+		}
+		if state == "running" {
+			return environmentRunning
+		}
+		status = environmentStopped
+	}
+	return status
+}
+
+func (dockerDriver) Services(environment Environment) ([]ServiceStatus, error) {
+	return containerServices("docker", environment)
+}
+
+func (dockerDriver) Stop(ctx context.Context, environment Environment) error {
+	return changeContainers(ctx, "docker", environment, "stop", "")
+}
+
+func (dockerDriver) Start(ctx context.Context, environment Environment) error {
+	return changeContainers(ctx, "docker", environment, "start", "")
+}
+
+func (dockerDriver) Restart(ctx context.Context, environment Environment, service string) error {
+	return changeContainers(ctx, "docker", environment, "restart", service)
+}
+
+func (dockerDriver) LogsCommand(ctx context.Context, environment Environment, service string, follow bool, tail int) (*exec.Cmd, error) {
+	return containerLogsCommand(ctx, "docker", environment, service, follow, tail)
+}
+
+// Build the logs command of docker or podman for the container of a service of an environment
+func containerLogsCommand(ctx context.Context, executable string, environment Environment, service string, follow bool, tail int) (*exec.Cmd, error) {
+	services, err := containerServices(executable, environment)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range services {
+		if s.Service != service && s.Name != service {
+			continue
+		}
+		args := []string{"logs", "--timestamps"}
+		if follow {
+			args = append(args, "--follow")
+		}
+		if tail > 0 {
+			args = append(args, "--tail", strconv.Itoa(tail))
+		}
+		return exec.CommandContext(ctx, executable, append(args, s.Name)...), nil
+	}
+	return nil, fmt.Errorf("service not found: %s", service)
+}
+
+// Run stop, start or restart on the containers of an environment with docker or podman.
+// If service is not empty, only on the containers of that service.
+func changeContainers(ctx context.Context, executable string, environment Environment, action string, service string) error {
+	services, err := containerServices(executable, environment)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, s := range services {
+		if service == "" || s.Service == service || s.Name == service {
+			names = append(names, s.Name)
+		}
+	}
+	if len(names) == 0 {
+		if service != "" {
+			return fmt.Errorf("service not found: %s", service)
+		}
+		return fmt.Errorf("no containers found for the environment %s %s", environment.EnvironmentSetup.Name, environment.EnvironmentSetup.Version)
+	}
+
+	dockerMethods.PrintTask(fmt.Sprintf("Running %s on the containers %s", action, strings.Join(names, ", ")))
+	if err := dockerMethods.ExecuteCommand(exec.CommandContext(ctx, executable, append([]string{action}, names...)...)); err != nil {
+		dockerMethods.PrintError("The " + action + " of the containers failed, cause: " + err.Error())
+		return err
+	}
+	return nil
+}
+
+// The fields of the output of docker (or podman) inspect used for the status of the services
+type containerInspect struct {
+	Name         string
+	RestartCount int
+	State        struct {
+		Status string
+		Health *struct {
+			Status string
+		}
+	}
+	Config struct {
+		Image  string
+		Labels map[string]string
+	}
+}
+
+// Get the containers of an environment with their state, from the inspect command of docker or podman
+func containerServices(executable string, environment Environment) ([]ServiceStatus, error) {
+	output, err := RunCommand(exec.Command(executable, "ps", "-a", "--format", "{{.Names}}"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing the containers: %w", err)
+	}
+	var names []string
+	for _, name := range strings.Fields(output) {
+		if isEnvironmentContainer(name, environment) {
+			names = append(names, name)
+		}
+	}
+	services := []ServiceStatus{}
+	if len(names) == 0 {
+		return services, nil
+	}
+
+	output, err = RunCommand(exec.Command(executable, append([]string{"inspect"}, names...)...))
+	if err != nil {
+		return nil, fmt.Errorf("error inspecting the containers: %w", err)
+	}
+	var containers []containerInspect
+	if err := json.Unmarshal([]byte(output), &containers); err != nil {
+		return nil, fmt.Errorf("error reading the containers: %w", err)
+	}
+
+	for _, container := range containers {
+		service := ServiceStatus{
+			Service:  container.Config.Labels["com.docker.compose.service"],
+			Name:     strings.TrimPrefix(container.Name, "/"),
+			State:    container.State.Status,
+			Restarts: container.RestartCount,
+			Image:    container.Config.Image,
+		}
+		if service.Service == "" {
+			service.Service = service.Name
+		}
+		if container.State.Health != nil {
+			service.Health = container.State.Health.Status
+		}
+		services = append(services, service)
+	}
+	sortServices(services)
+	return services, nil
+}
+
+// Get the prefix used by the docker cmd for the names of the containers of an environment
+func dockerProjectName(environment Environment) string {
+	return regexp.MustCompile(`[^a-zA-Z0-9 ]+`).ReplaceAllString(environment.EnvironmentSetup.Name+environment.EnvironmentSetup.Version, "-")
+}
+
+// The names of the containers in the compose file of the docker cmd, without the prefix of the environment
+var composeContainerNames = sync.OnceValue(func() map[string]bool {
+	names := make(map[string]bool)
+	for _, match := range regexp.MustCompile(`container_name:\s*\$\{PREFIX\}(\S+)`).FindAllSubmatch(dockerMethods.GetDockerComposeEmbed(), -1) {
+		names[string(match[1])] = true
+	}
+	return names
+})
+
+// Check if a container belongs to an environment: its name is the prefix of the environment followed by the name of a
+// container of the compose file. Only checking the prefix would also match the containers of test/1.0.1 for test/1.0.
+func isEnvironmentContainer(name string, environment Environment) bool {
+	service, found := strings.CutPrefix(name, dockerProjectName(environment)+"-")
+	return found && composeContainerNames()[service]
+}
+
+// The container used by the docker and kubernetes cmds to serve the files to populate
+const metadataCacheResource = "docker container tmc"
+
+// Remove the container used by the docker and kubernetes cmds to serve the files to populate,
+// they leave it behind if the populate is interrupted
+func removeMetadataCacheContainer() {
+	RunCommand(exec.Command("docker", "rm", "-f", "tmc"))
+}
+
+// Check if docker compose (the plugin or the standalone docker-compose) is installed
+func isDockerComposeInstalled() bool {
+	// Run "docker compose --version", if it fails, run "docker-compose --version"
+	_, err := RunCommand(exec.Command("docker", "compose", "--version"))
+	if err != nil {
+		_, err = RunCommand(exec.Command("docker-compose", "--version"))
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Check if the docker daemon is running
+func isDockerRunning() bool {
+	_, err := RunCommand(exec.Command("docker", "info"))
+	return err == nil
+}
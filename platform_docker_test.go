@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestIsEnvironmentContainer(t *testing.T) {
+	environment := Environment{EnvironmentSetup: EnvironmentSetup{Name: "test", Version: "1.0"}}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"test1-0-gateway", true},
+		{"test1-0-data-portal", true},
+		// The containers of test/1.0.1 and mytest/1.0
+		{"test1-0-1-gateway", false},
+		{"mytest1-0-gateway", false},
+		{"test1-0-unknown", false},
+		{"test1-0-", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isEnvironmentContainer(tt.name, environment); got != tt.want {
+			t.Errorf("isEnvironmentContainer(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Environments whose names overlap, the containers of test/1.0 are stopped while the ones of the others are running
+func TestContainersStatusOverlappingEnvironments(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake docker is a shell script")
+	}
+	executable := filepath.Join(t.TempDir(), "docker")
+	script := "#!/bin/sh\nprintf 'test1-0-gateway\\texited\\ntest1-0-1-gateway\\trunning\\nmytest1-0-rabbitmq\\trunning\\n'\n"
+	if err := os.WriteFile(executable, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		want    string
+	}{
+		{"test", "1.0", environmentStopped},
+		{"test", "1.0.1", environmentRunning},
+		{"mytest", "1.0", environmentRunning},
+		{"test", "2.0", environmentMissing},
+	}
+	for _, tt := range tests {
+		environment := Environment{EnvironmentSetup: EnvironmentSetup{Name: tt.name, Version: tt.version}}
+		if got := containersStatus(executable, environment); got != tt.want {
+			t.Errorf("status of %s/%s = %q, want %q", tt.name, tt.version, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	kubernetesMethods "github.com/epos-eu/opensource-kubernetes/cmd/methods"
+)
+
+func init() {
+	registerPlatformDriver(kubernetesDriver{})
+}
+
+// PlatformDriver installing the environments in a namespace of a kubernetes cluster
+type kubernetesDriver struct{}
+
+func (kubernetesDriver) Name() string {
+	return "kubernetes"
+}
+
+func (kubernetesDriver) Prerequisites() error {
+	if !isKubectlInstalled() {
+		return fmt.Errorf("kubectl is not installed")
+	}
+	return nil
+}
+
+func (kubernetesDriver) DefaultVariables() ([]Section, error) {
+	// Read the env file used by the kubernetes cmd
+	return readEnvFile(kubernetesMethods.GetConfigurationsEmbed())
+}
+
+func (k kubernetesDriver) Install(ctx context.Context, envFilePath string, environment Environment, autoUpdateImages bool, isEdit bool) error {
+	err := runCancellable(ctx, func() error {
+		return kubernetesMethods.CreateEnvironment(
+			envFilePath,                          // the file with the environment variables
+			environment.EnvironmentSetup.Context, // the context
+			environment.EnvironmentSetup.Name,    // the namespace
+			environment.EnvironmentSetup.Version, // the version of the environment
+			fmt.Sprintf("%t", autoUpdateImages),  // if the images should be updated
+			fmt.Sprintf("%t", isEdit),            // if the environment is being edited/updated
+		)
+	})
+
+	// Remove the namespace created before the cancellation, an environment being edited is left as it is
+	if ctx.Err() != nil && !isEdit {
+		kubernetesMethods.PrintTask("Installation cancelled, removing the namespace " + environment.EnvironmentSetup.Name)
+		k.Delete(context.Background(), envFilePath, environment)
+	}
+	return err
+}
+
+func (kubernetesDriver) AccessPoints(environment Environment) EposAccessPoints {
+	// The kubernetes cmd exports the urls it used as environment variables
+	return EposAccessPoints{
+		DataPortal: os.Getenv("PORTAL_URL_READY"),
+		ApiGateway: os.Getenv("API_URL_READY"),
+	}
+}
+
+func (kubernetesDriver) Populate(ctx context.Context, envFilePath string, path string, environment Environment) error {
+	if environment.EnvironmentSetup.Context == "" {
+		return fmt.Errorf("context not found: %s %s", environment.EnvironmentSetup.Name, environment.EnvironmentSetup.Version)
+	}
+
+	err := runCancellable(ctx, func() error {
+		return kubernetesMethods.PopulateEnvironment(
+			environment.EnvironmentSetup.Context, // kubernetes context
+			envFilePath,                          // environment variables file path
+			path,                                 // path to the files to populate
+			environment.EnvironmentSetup.Name,    // environment name (namespace)
+			environment.EnvironmentSetup.Version, // environment tag
+		)
+	})
+
+	// The files are served to the cluster from a local docker container
+	if ctx.Err() != nil {
+		removeMetadataCacheContainer()
+	}
+	return err
+}
+
+func (kubernetesDriver) Delete(ctx context.Context, envFilePath string, environment Environment) error {
+	return runCancellable(ctx, func() error {
+		return kubernetesMethods.DeleteEnvironment(
+			environment.EnvironmentSetup.Context, // kubernetes context
+			environment.EnvironmentSetup.Name,    // namespace
+		)
+	})
+}
+
+// The kubernetes cmd switches the current context of kubectl before its commands, so only one operation can run at a time.
+// The populate also serves the files with the same docker container as the docker cmd.
+// The stop, start and restart give the context to kubectl and can run at any time, the images don't use the cluster.
+func (kubernetesDriver) SharedResources(operation string) []string {
+	switch operation {
+	case "populate":
+		return []string{kubectlContextResource, metadataCacheResource}
+	case "stop", "start", "restart", "export-images", "load-images":
+		return nil
+	}
+	return []string{kubectlContextResource}
+}
+
+// The number of [TASK] lines printed by the kubernetes cmd
+func (kubernetesDriver) ProgressSteps(operation string) int {
+	if operation == "install" {
+		// Switching context, namespace check, 13 services and the gateway restart
+		return 16
+	}
+	return 0
+}
+
+func (kubernetesDriver) Status(environment Environment) string {
+	kubeContext := environment.EnvironmentSetup.Context
+	if kubeContext == "" || !isKubectlInstalled() {
+		return environmentUnknown
+	}
+	// The context was removed from the kubeconfig, the cluster can't be reached anymore
+	if _, err := RunCommand(exec.Command("kubectl", "config", "get-contexts", kubeContext)); err != nil {
+		return environmentUnreachable
+	}
+
+	namespace, err := runKubectl(kubeContext, "get", "namespace", environment.EnvironmentSetup.Name, "--ignore-not-found", "-o", "name")
+	if err != nil {
+		return environmentUnreachable
+	}
+	if strings.TrimSpace(namespace) == "" {
+		return environmentMissing
+	}
+
+	// The deployments scaled to zero by Stop
+	deployments, err := kubernetesDeployments(environment)
+	if err != nil {
+		return environmentUnknown
+	}
+	stopped := len(deployments) > 0
+	for _, deployment := range deployments {
+		stopped = stopped && deployment.Spec.Replicas == 0
+	}
+	if stopped {
+		return environmentStopped
+	}
+
+	pods, err := runKubectl(kubeContext, "get", "pods", "--namespace", environment.EnvironmentSetup.Name, "--field-selector", "status.phase=Running", "-o", "name")
+	if err != nil {
+		return environmentUnknown
+	}
+	if strings.TrimSpace(pods) == "" {
+		return environmentStopped
+	}
+	return environmentRunning
+}
+
+func (kubernetesDriver) Services(environment Environment) ([]ServiceStatus, error) {
+	output, err := runKubectl(environment.EnvironmentSetup.Context, "get", "pods", "--namespace", environment.EnvironmentSetup.Name, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("error listing the pods: %w", err)
+	}
+	var pods struct {
+		Items []kubernetesPod
+	}
+	if err := json.Unmarshal([]byte(output), &pods); err != nil {
+		return nil, fmt.Errorf("error reading the pods: %w", err)
+	}
+
+	services := []ServiceStatus{}
+	for _, pod := range pods.Items {
+		services = append(services, pod.serviceStatus())
+	}
+	sortServices(services)
+	return services, nil
+}
+
+// The annotation with the replicas of a deployment before Stop scaled it to zero, used by Start to scale it back
+const replicasAnnotation = "epos-desktop/replicas"
+
+// Scale the deployments of the namespace to zero, the replicas they had are kept in an annotation
+func (kubernetesDriver) Stop(ctx context.Context, environment Environment) error {
+	deployments, err := kubernetesDeployments(environment)
+	if err != nil {
+		return err
+	}
+	for _, deployment := range deployments {
+		if deployment.Spec.Replicas == 0 {
+			continue
+		}
+		kubernetesMethods.PrintTask("Scaling the deployment " + deployment.Metadata.Name + " to zero")
+		annotation := fmt.Sprintf("%s=%d", replicasAnnotation, deployment.Spec.Replicas)
+		if err := runKubectlOperation(ctx, environment, "annotate", "deployment", deployment.Metadata.Name, annotation, "--overwrite"); err != nil {
+			return err
+		}
+		if err := runKubectlOperation(ctx, environment, "scale", "deployment", deployment.Metadata.Name, "--replicas=0"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Scale the deployments scaled to zero by Stop back to the replicas they had
+func (kubernetesDriver) Start(ctx context.Context, environment Environment) error {
+	deployments, err := kubernetesDeployments(environment)
+	if err != nil {
+		return err
+	}
+	for _, deployment := range deployments {
+		if deployment.Spec.Replicas != 0 {
+			continue
+		}
+		replicas, err := strconv.Atoi(deployment.Metadata.Annotations[replicasAnnotation])
+		if err != nil || replicas < 1 {
+			replicas = 1
+		}
+		kubernetesMethods.PrintTask(fmt.Sprintf("Scaling the deployment %s to %d", deployment.Metadata.Name, replicas))
+		if err := runKubectlOperation(ctx, environment, "scale", "deployment", deployment.Metadata.Name, fmt.Sprintf("--replicas=%d", replicas)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restart the pods of the deployment named service, or of all the deployments of the namespace
+func (kubernetesDriver) Restart(ctx context.Context, environment Environment, service string) error {
+	target := []string{"deployment", "--all"}
+	if service != "" {
+		target = []string{"deployment/" + service}
+	}
+	kubernetesMethods.PrintTask("Restarting " + strings.Join(target, " "))
+	return runKubectlOperation(ctx, environment, append([]string{"rollout", "restart"}, target...)...)
+}
+
+// Print the logs of all the containers of the first pod of the service
+func (k kubernetesDriver) LogsCommand(ctx context.Context, environment Environment, service string, follow bool, tail int) (*exec.Cmd, error) {
+	services, err := k.Services(environment)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range services {
+		if s.Service != service && s.Name != service {
+			continue
+		}
+		// Not built with kubectlCommand, its request timeout would stop the logs being followed
+		args := []string{"--context", environment.EnvironmentSetup.Context, "--namespace", environment.EnvironmentSetup.Name,
+			"logs", s.Name, "--all-containers", "--timestamps"}
+		if follow {
+			args = append(args, "--follow")
+		}
+		if tail > 0 {
+			args = append(args, "--tail", strconv.Itoa(tail))
+		}
+		return exec.CommandContext(ctx, "kubectl", args...), nil
+	}
+	return nil, fmt.Errorf("service not found: %s", service)
+}
+
+// The fields of the deployments listed by kubectl used to stop and start them
+type kubernetesDeployment struct {
+	Metadata struct {
+		Name        string
+		Annotations map[string]string
+	}
+	Spec struct {
+		Replicas int
+	}
+}
+
+// Get the deployments in the namespace of the environment
+func kubernetesDeployments(environment Environment) ([]kubernetesDeployment, error) {
+	output, err := runKubectl(environment.EnvironmentSetup.Context, "get", "deployments", "--namespace", environment.EnvironmentSetup.Name, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("error listing the deployments: %w", err)
+	}
+	var deployments struct {
+		Items []kubernetesDeployment
+	}
+	if err := json.Unmarshal([]byte(output), &deployments); err != nil {
+		return nil, fmt.Errorf("error reading the deployments: %w", err)
+	}
+	return deployments.Items, nil
+}
+
+// Run a kubectl command in the namespace of the environment printing its output, killed when ctx is done
+func runKubectlOperation(ctx context.Context, environment Environment, args ...string) error {
+	cmd := kubectlCommand(ctx, environment.EnvironmentSetup.Context, append([]string{"--namespace", environment.EnvironmentSetup.Name}, args...)...)
+	if err := kubernetesMethods.ExecuteCommand(cmd); err != nil {
+		kubernetesMethods.PrintError("kubectl " + strings.Join(args, " ") + " failed, cause: " + err.Error())
+		return err
+	}
+	return nil
+}
+
+// The fields of the pods listed by kubectl used for the status of the services
+type kubernetesPod struct {
+	Metadata struct {
+		Name            string
+		OwnerReferences []struct {
+			Kind string
+			Name string
+		}
+	}
+	Status struct {
+		Phase             string
+		ContainerStatuses []struct {
+			Image        string
+			Ready        bool
+			RestartCount int
+			State        struct {
+				Waiting *struct {
+					Reason string
+				}
+				Terminated *struct {
+					Reason string
+				}
+			}
+		}
+	}
+}
+
+func (pod kubernetesPod) serviceStatus() ServiceStatus {
+	service := ServiceStatus{Service: pod.Metadata.Name, Name: pod.Metadata.Name, State: pod.Status.Phase}
+
+	// The pods of a deployment belong to a replica set named after it, followed by a hash
+	for _, owner := range pod.Metadata.OwnerReferences {
+		service.Service = owner.Name
+		if owner.Kind == "ReplicaSet" {
+			if i := strings.LastIndex(owner.Name, "-"); i > 0 {
+				service.Service = owner.Name[:i]
+			}
+		}
+	}
+
+	ready := len(pod.Status.ContainerStatuses) > 0
+	var images []string
+	for _, container := range pod.Status.ContainerStatuses {
+		images = append(images, container.Image)
+		service.Restarts += container.RestartCount
+		ready = ready && container.Ready
+		// A container that can't start is more telling than the phase of the pod
+		if container.State.Waiting != nil && container.State.Waiting.Reason != "" {
+			service.State = container.State.Waiting.Reason
+		} else if container.State.Terminated != nil && container.State.Terminated.Reason != "" && pod.Status.Phase == "Running" {
+			service.State = container.State.Terminated.Reason
+		}
+	}
+	service.Image = strings.Join(images, ", ")
+
+	// The readiness probes are the health checks of the pods
+	if pod.Status.Phase == "Running" {
+		service.Health = "unhealthy"
+		if ready {
+			service.Health = "healthy"
+		}
+	}
+	return service
+}
+
+// Run a kubectl command on the cluster of the context and return its output
+func runKubectl(kubeContext string, args ...string) (string, error) {
+	return RunCommand(kubectlCommand(context.Background(), kubeContext, args...))
+}
+
+// Build a kubectl command on the cluster of the context, killed when ctx is done. The context is given to the command
+// instead of switching the current one, that is used by the operations of the kubernetes cmd.
+func kubectlCommand(ctx context.Context, kubeContext string, args ...string) *exec.Cmd {
+	args = append([]string{"--context", kubeContext, "--request-timeout", "10s"}, args...)
+	return exec.CommandContext(ctx, "kubectl", args...)
+}
+
+// The current context of kubectl, set by the kubernetes cmd
+const kubectlContextResource = "kubectl current context"
+
+// Check if the kubectl client is installed
+func isKubectlInstalled() bool {
+	_, err := RunCommand(exec.Command("kubectl", "version", "--client"))
+	return err == nil
+}
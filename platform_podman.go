@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	dockerMethods "github.com/epos-eu/opensource-docker/cmd/methods"
+	"github.com/joho/godotenv"
+)
+
+func init() {
+	registerPlatformDriver(podmanDriver{})
+}
+
+// PlatformDriver installing the environments with podman, using the same compose file and variables as docker.
+// The docker cmd only knows about the docker executables, so the compose commands are run here.
+type podmanDriver struct{}
+
+func (podmanDriver) Name() string {
+	return "podman"
+}
+
+func (podmanDriver) Prerequisites() error {
+	if !isPodmanInstalled() {
+		return fmt.Errorf("podman is not installed")
+	}
+	if _, _, err := getPodmanComposeCommand(); err != nil {
+		return err
+	}
+	if !isPodmanRunning() {
+		return fmt.Errorf("podman is not running (on macOS and Windows the podman machine must be started)")
+	}
+	return nil
+}
+
+func (podmanDriver) DefaultVariables() ([]Section, error) {
+	// The same stack as docker is installed, so the variables are the same
+	return readEnvFile(dockerMethods.GetConfigurationsEmbed())
+}
+
+func (p podmanDriver) Install(ctx context.Context, envFilePath string, environment Environment, autoUpdateImages bool, isEdit bool) error {
+	compose, err := preparePodmanCompose(envFilePath, environment)
+	if err != nil {
+		return err
+	}
+	defer compose.cleanup()
+
+	// Remove the containers created before the cancellation, an environment being edited is left as it is
+	defer func() {
+		if ctx.Err() != nil && !isEdit {
+			dockerMethods.PrintTask("Installation cancelled, removing the created containers")
+			dockerMethods.ExecuteCommand(compose.command(context.Background(), "down", "-v"))
+		}
+	}()
+
+	// Use the same steps as the docker cmd to configure the environment
+	if autoUpdateImages {
+		if err := dockerMethods.CheckImagesUpdate(); err != nil {
+			dockerMethods.PrintError("Error on updating the container images " + err.Error())
+			return err
+		}
+	}
+	if err := dockerMethods.OverridePorts(strconv.FormatBool(isEdit)); err != nil {
+		dockerMethods.PrintError("Error during overriding ports if update=true " + err.Error())
+		return err
+	}
+	address, err := environmentAddress(environment)
+	if err != nil {
+		dockerMethods.PrintError("Error on setting the IPs " + err.Error())
+		return err
+	}
+	setupHostVariables(address)
+	dockerMethods.PrintSetup(envFilePath, compose.file)
+
+	// Start the message bus and the database first, the other services need them to be ready
+	steps := []struct {
+		task string
+		args []string
+		wait time.Duration
+	}{
+		{"Installing rabbitmq container on the machine", []string{"up", "-d", "rabbitmq"}, 15 * time.Second},
+		{"Installing metadata catalogue container on the machine", []string{"up", "-d", "metadatacatalogue"}, 15 * time.Second},
+		{"Installing all remaining containers on the machine", []string{"up", "-d"}, 40 * time.Second},
+		{"Restarting gateway", []string{"restart", "gateway"}, 5 * time.Second},
+	}
+	for _, step := range steps {
+		dockerMethods.PrintTask(step.task)
+		if err := dockerMethods.ExecuteCommand(compose.command(ctx, step.args...)); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			dockerMethods.PrintError(step.task + " failed, cause: " + err.Error())
+			return err
+		}
+		if err := sleepContext(ctx, step.wait); err != nil {
+			return err
+		}
+	}
+
+	dockerMethods.PrintUrls()
+	return nil
+}
+
+func (podmanDriver) AccessPoints(environment Environment) EposAccessPoints {
+	// The environment is configured like the docker one
+	return dockerDriver{}.AccessPoints(environment)
+}
+
+func (podmanDriver) Populate(ctx context.Context, envFilePath string, path string, environment Environment) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		dockerMethods.PrintError("Loading file folder, cause: " + err.Error())
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("you need to define a folder: %s", path)
+	}
+
+	compose, err := preparePodmanCompose(envFilePath, environment)
+	if err != nil {
+		return err
+	}
+	defer compose.cleanup()
+
+	address, err := environmentAddress(environment)
+	if err != nil {
+		dockerMethods.PrintError("Error on setting the IPs " + err.Error())
+		return err
+	}
+	setupHostVariables(address)
+
+	// Serve the files with a temporary nginx container so that the ingestor can download them
+	freePort, err := dockerMethods.GetFreePort()
+	if err != nil {
+		return err
+	}
+	cacheContainer := dockerProjectName(environment) + "-metadata-cache"
+	err = dockerMethods.ExecuteCommand(exec.CommandContext(ctx, "podman", "run", "-d",
+		"--name", cacheContainer,
+		"-p", strconv.Itoa(freePort)+":80",
+		"-v", strings.TrimSpace(path)+":/usr/share/nginx/html:ro",
+		"docker.io/library/nginx"))
+	if err != nil {
+		dockerMethods.PrintError("Creating metadata-cache container, cause " + err.Error())
+		return err
+	}
+	defer dockerMethods.ExecuteCommand(exec.Command("podman", "rm", "-f", cacheContainer))
+
+	// Ask the ingestor to load every ttl file
+	ingestorUrl := "http://" + os.Getenv("LOCAL_IP") + ":" + os.Getenv("API_PORT") + os.Getenv("DEPLOY_PATH") + os.Getenv("API_PATH") + "/ingestor"
+	err = filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".ttl") {
+			return nil
+		}
+
+		dockerMethods.PrintTask("Ingestion file into database: " + info.Name())
+		request, err := http.NewRequestWithContext(ctx, "POST", ingestorUrl, nil)
+		if err != nil {
+			return err
+		}
+		request.Header.Add("accept", "*/*")
+		request.Header.Add("path", "http://"+os.Getenv("LOCAL_IP")+":"+strconv.Itoa(freePort)+"/"+info.Name())
+		request.Header.Add("securityCode", "changeme")
+		request.Header.Add("type", "single")
+		request.Header.Add("model", "EPOS-DCAT-AP-V1")
+
+		response, err := networkHTTPClient().Do(request)
+		if err != nil {
+			dockerMethods.PrintError("Ingestion failed, cause " + err.Error())
+			return err
+		}
+		response.Body.Close()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// The converter has to be restarted to see the new data
+	if err := dockerMethods.ExecuteCommand(compose.command(ctx, "restart", "converterservice")); err != nil {
+		dockerMethods.PrintError("Error restarting converter service, cause " + err.Error())
+		return err
+	}
+
+	dockerMethods.PrintUrls()
+	return nil
+}
+
+func (podmanDriver) Delete(ctx context.Context, envFilePath string, environment Environment) error {
+	compose, err := preparePodmanCompose(envFilePath, environment)
+	if err != nil {
+		return err
+	}
+	defer compose.cleanup()
+
+	if err := dockerMethods.ExecuteCommand(compose.command(ctx, "down", "-v")); err != nil {
+		dockerMethods.PrintError("Deletion of the containers failed, cause: " + err.Error())
+		return err
+	}
+	return nil
+}
+
+// The number of [TASK] lines printed while installing, the same as docker
+func (podmanDriver) ProgressSteps(operation string) int {
+	if operation == "install" {
+		return 4
+	}
+	return 0
+}
+
+func (podmanDriver) Status(environment Environment) string {
+	return containersStatus("podman", environment)
+}
+
+func (podmanDriver) Services(environment Environment) ([]ServiceStatus, error) {
+	return containerServices("podman", environment)
+}
+
+func (podmanDriver) LogsCommand(ctx context.Context, environment Environment, service string, follow bool, tail int) (*exec.Cmd, error) {
+	return containerLogsCommand(ctx, "podman", environment, service, follow, tail)
+}
+
+func (podmanDriver) Stop(ctx context.Context, environment Environment) error {
+	return changeContainers(ctx, "podman", environment, "stop", "")
+}
+
+func (podmanDriver) Start(ctx context.Context, environment Environment) error {
+	return changeContainers(ctx, "podman", environment, "start", "")
+}
+
+func (podmanDriver) Restart(ctx context.Context, environment Environment, service string) error {
+	return changeContainers(ctx, "podman", environment, "restart", service)
+}
+
+// A compose file written for one podman compose call
+type podmanCompose struct {
+	// The folder holding the compose file
+	dir string
+	// The compose file
+	file string
+	// The executable and the arguments that run compose (podman compose or podman-compose)
+	executable string
+	args       []string
+	// The compose project, the same for every call on an environment so that they all act on its containers
+	project string
+}
+
+// Write the compose file of the EPOS stack and load the variables of the environment in the process environment,
+// like the docker cmd does, so that compose can substitute them
+func preparePodmanCompose(envFilePath string, environment Environment) (*podmanCompose, error) {
+	executable, args, err := getPodmanComposeCommand()
+	if err != nil {
+		return nil, err
+	}
+
+	// The prefix of the container names, as set by the docker cmd
+	os.Setenv("PREFIX", dockerProjectName(environment)+"-")
+
+	dir, err := os.MkdirTemp("", "epos-podman-")
+	if err != nil {
+		return nil, err
+	}
+	file := filepath.Join(dir, "docker-compose.yaml")
+	if err := os.WriteFile(file, environmentComposeFile(environment), 0600); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	if err := godotenv.Overload(envFilePath); err != nil {
+		os.RemoveAll(dir)
+		dockerMethods.PrintError("Loading env variables from " + envFilePath + " cause: " + err.Error())
+		return nil, err
+	}
+
+	return &podmanCompose{dir: dir, file: file, executable: executable, args: args, project: podmanProjectName(environment)}, nil
+}
+
+// Get the compose project of an environment, compose only accepts lowercase letters, digits, "-" and "_" in it.
+// Without it compose names the project after the folder of the compose file, which is a new one on every call.
+func podmanProjectName(environment Environment) string {
+	return strings.TrimLeft(strings.ToLower(strings.ReplaceAll(dockerProjectName(environment), " ", "")), "-")
+}
+
+// Build a compose command on the compose file, killed when ctx is done, e.g. command(ctx, "up", "-d")
+func (c *podmanCompose) command(ctx context.Context, args ...string) *exec.Cmd {
+	fullArgs := append([]string{}, c.args...)
+	fullArgs = append(fullArgs, "-p", c.project, "-f", c.file)
+	fullArgs = append(fullArgs, args...)
+	return exec.CommandContext(ctx, c.executable, fullArgs...)
+}
+
+func (c *podmanCompose) cleanup() {
+	os.RemoveAll(c.dir)
+}
+
+// Get the command to use for compose: "podman compose" if available, "podman-compose" otherwise
+func getPodmanComposeCommand() (string, []string, error) {
+	if _, err := RunCommand(exec.Command("podman", "compose", "version")); err == nil {
+		return "podman", []string{"compose"}, nil
+	}
+	if _, err := RunCommand(exec.Command("podman-compose", "version")); err == nil {
+		return "podman-compose", nil, nil
+	}
+	return "", nil, fmt.Errorf("no valid podman compose or podman-compose installation found")
+}
+
+// Check if the podman client is installed
+func isPodmanInstalled() bool {
+	_, err := RunCommand(exec.Command("podman", "--version"))
+	return err == nil
+}
+
+// Check if podman can run containers (on macOS and Windows this needs a running podman machine)
+func isPodmanRunning() bool {
+	_, err := RunCommand(exec.Command("podman", "info"))
+	return err == nil
+}
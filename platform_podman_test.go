@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestPodmanProjectName(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		want    string
+	}{
+		{"epos", "1.0", "epos1-0"},
+		{"My Env", "2.1.0", "myenv2-1-0"},
+		{"_test", "v1", "testv1"},
+	}
+	for _, tt := range tests {
+		environment := Environment{EnvironmentSetup: EnvironmentSetup{Name: tt.name, Version: tt.version}}
+		if got := podmanProjectName(environment); got != tt.want {
+			t.Errorf("podmanProjectName(%q, %q) = %q, want %q", tt.name, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestPodmanComposeCommand(t *testing.T) {
+	environment := Environment{EnvironmentSetup: EnvironmentSetup{Name: "epos", Version: "1.0"}}
+	tests := []struct {
+		name       string
+		executable string
+		args       []string
+		want       []string
+	}{
+		{"podman compose", "podman", []string{"compose"}, []string{"podman", "compose", "-p", "epos1-0", "-f", "/tmp/epos/docker-compose.yaml", "down", "-v"}},
+		{"podman-compose", "podman-compose", nil, []string{"podman-compose", "-p", "epos1-0", "-f", "/tmp/epos/docker-compose.yaml", "down", "-v"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compose := &podmanCompose{file: "/tmp/epos/docker-compose.yaml", executable: tt.executable, args: tt.args, project: podmanProjectName(environment)}
+			cmd := compose.command(context.Background(), "down", "-v")
+			if !reflect.DeepEqual(cmd.Args, tt.want) {
+				t.Errorf("got %q, want %q", cmd.Args, tt.want)
+			}
+		})
+	}
+}
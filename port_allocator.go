@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dockerMethods "github.com/epos-eu/opensource-docker/cmd/methods"
+)
+
+// The setting with the port range as json, the default one if empty
+const portRangeSetting = "portRange"
+
+// The time given to a connection to the loopback to tell if a program listens on a port
+const portDialTimeout = 200 * time.Millisecond
+
+// PortRange is where the free ports are looked for when assigning the ports of the environments
+type PortRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// Refuse the ports outside of the range, e.g. when only the range is open in the firewall.
+	// Otherwise a port outside of the range can still be chosen by hand.
+	Strict bool `json:"strict"`
+}
+
+// The range used when none was set, it has the default ports of the env.env file
+var defaultPortRange = PortRange{Start: 30000, End: 39999}
+
+func (r PortRange) contains(port int) bool {
+	return port >= r.Start && port <= r.End
+}
+
+// Get the range where the free ports are looked for
+func (a *App) GetPortRange() (PortRange, error) {
+	value, err := a.store.GetSetting(portRangeSetting)
+	if err != nil {
+		return PortRange{}, err
+	}
+	if value == "" {
+		return defaultPortRange, nil
+	}
+
+	var portRange PortRange
+	if err := json.Unmarshal([]byte(value), &portRange); err != nil {
+		return PortRange{}, fmt.Errorf("error reading the port range: %w", err)
+	}
+	return portRange, nil
+}
+
+// Save the range where the free ports are looked for, the default one is used again if it is empty
+func (a *App) SetPortRange(portRange PortRange) error {
+	if portRange == (PortRange{}) {
+		return a.store.SetSetting(portRangeSetting, "")
+	}
+	if portRange.Start < 1 || portRange.End > 65535 || portRange.Start > portRange.End {
+		return fmt.Errorf("the port range must be between 1 and 65535 and start before it ends: %d-%d", portRange.Start, portRange.End)
+	}
+
+	data, err := json.Marshal(portRange)
+	if err != nil {
+		return err
+	}
+	return a.store.SetSetting(portRangeSetting, string(data))
+}
+
+// Check if the port can be used by the environment with the given id (empty for a new environment): it is not
+// reserved by another environment, no other program listens on it and it is in the range if the range is strict
+func (a *App) IsPortAvailable(environmentId, port string) (bool, error) {
+	// Validate the string
+	portInt, err := strconv.Atoi(port)
+	if err != nil || portInt < 1 || portInt > 65535 {
+		return false, err
+	}
+
+	allocator, err := a.newPortAllocator(environmentId)
+	if err != nil {
+		return false, err
+	}
+	return allocator.unavailable(portInt) == "", nil
+}
+
+// Get the first port of the range that a new environment can use
+func (a *App) GetAvailablePort() (string, error) {
+	allocator, err := a.newPortAllocator("")
+	if err != nil {
+		return "", err
+	}
+	port, err := allocator.next()
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(port), nil
+}
+
+// Give a free port of the range to each port variable of the platform that can't keep its value, e.g. the default
+// ports taken by another environment. The environment id is empty for a new environment, the ports it reserved are
+// kept when it is edited. Returns a copy of the variables with the ports assigned.
+func (a *App) AssignPorts(environmentId, platform string, variables []Section) ([]Section, error) {
+	allocator, err := a.newPortAllocator(environmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	// The ports that can be kept are taken first, so that they are not given to another variable
+	assigned := copySections(variables)
+	type portVariable struct {
+		variables map[string]string
+		name      string
+	}
+	var pending []portVariable
+	for _, name := range portVariables(platform) {
+		for _, section := range assigned {
+			value, ok := section.Variables[name]
+			if !ok {
+				continue
+			}
+			if port, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && allocator.unavailable(port) == "" {
+				allocator.taken[port] = true
+				continue
+			}
+			pending = append(pending, portVariable{section.Variables, name})
+		}
+	}
+
+	for _, variable := range pending {
+		port, err := allocator.next()
+		if err != nil {
+			return nil, err
+		}
+		variable.variables[variable.name] = strconv.Itoa(port)
+	}
+	return assigned, nil
+}
+
+// The ports the environment with the given id can't use, as errors of their variables
+func (a *App) checkPorts(environmentId, platform string, variables []Section) ([]VariableError, error) {
+	allocator, err := a.newPortAllocator(environmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	portErrors := []VariableError{}
+	for _, reservation := range portReservationsOf(platform, variables) {
+		if message := allocator.unavailable(reservation.Port); message != "" {
+			portErrors = append(portErrors, VariableError{Section: sectionOfVariable(variables, reservation.Variable), Variable: reservation.Variable, Message: message})
+		}
+	}
+	return portErrors, nil
+}
+
+// The variables with the ports published on the machine by the environments of the platform, from the compose file
+// of the docker cmd. The services of the kubernetes environments are published by the cluster.
+func portVariables(platform string) []string {
+	if platform == "kubernetes" {
+		return nil
+	}
+	var names []string
+	for _, match := range composePortPattern.FindAllSubmatch(dockerMethods.GetDockerComposeEmbed(), -1) {
+		names = append(names, string(match[3]))
+	}
+	return names
+}
+
+// The ports the environment publishes on the machine, the values that are not ports are left out (the schema reports them)
+func portReservationsOf(platform string, variables []Section) []PortReservation {
+	var reservations []PortReservation
+	for _, name := range portVariables(platform) {
+		for _, section := range variables {
+			value, ok := section.Variables[name]
+			if !ok {
+				continue
+			}
+			if port, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && port >= 1 && port <= 65535 {
+				reservations = append(reservations, PortReservation{Port: port, Variable: name})
+			}
+		}
+	}
+	return reservations
+}
+
+// The name of the section with the variable, empty if there is none
+func sectionOfVariable(variables []Section, name string) string {
+	for _, section := range variables {
+		if _, ok := section.Variables[name]; ok {
+			return section.Name
+		}
+	}
+	return ""
+}
+
+// Chooses and checks the ports of an environment against the range, the reservations of the other environments
+// and the ports other programs listen on
+type portAllocator struct {
+	portRange     PortRange
+	environmentId string
+	reserved      map[int]PortReservation
+	// The name and version of the environments, by id
+	environmentNames map[string]string
+	// The ports already given to the variables of the environment
+	taken map[int]bool
+}
+
+func (a *App) newPortAllocator(environmentId string) (*portAllocator, error) {
+	portRange, err := a.GetPortRange()
+	if err != nil {
+		return nil, err
+	}
+	reservations, err := a.store.GetPortReservations()
+	if err != nil {
+		return nil, err
+	}
+	environments, err := a.store.GetEnvironments()
+	if err != nil {
+		return nil, err
+	}
+
+	allocator := &portAllocator{
+		portRange:        portRange,
+		environmentId:    environmentId,
+		reserved:         make(map[int]PortReservation),
+		environmentNames: make(map[string]string),
+		taken:            make(map[int]bool),
+	}
+	for _, reservation := range reservations {
+		allocator.reserved[reservation.Port] = reservation
+	}
+	for _, environment := range environments {
+		allocator.environmentNames[environment.ID] = environment.EnvironmentSetup.Name + " " + environment.EnvironmentSetup.Version
+	}
+	return allocator, nil
+}
+
+// Why the environment can't use the port, empty if it can
+func (p *portAllocator) unavailable(port int) string {
+	if port < 1 || port > 65535 {
+		return "is not a valid port"
+	}
+	if p.portRange.Strict && !p.portRange.contains(port) {
+		return fmt.Sprintf("is outside of the allowed ports (%d-%d)", p.portRange.Start, p.portRange.End)
+	}
+	if p.taken[port] {
+		return "is already used by another variable"
+	}
+	if reservation, ok := p.reserved[port]; ok {
+		// The containers of the environment listen on its own ports
+		if reservation.EnvironmentID == p.environmentId {
+			return ""
+		}
+		name, ok := p.environmentNames[reservation.EnvironmentID]
+		if !ok {
+			name = reservation.EnvironmentID
+		}
+		return "is used by the environment " + name
+	}
+	if portListened(port) {
+		return "is used by another program"
+	}
+	return ""
+}
+
+// Take the first port of the range the environment can use
+func (p *portAllocator) next() (int, error) {
+	for port := p.portRange.Start; port <= p.portRange.End; port++ {
+		if p.unavailable(port) == "" {
+			p.taken[port] = true
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("there is no free port between %d and %d", p.portRange.Start, p.portRange.End)
+}
+
+// If a program listens on the port of the machine, on IPv4 or IPv6
+func portListened(port int) bool {
+	address := ":" + strconv.Itoa(port)
+	listener, err := net.Listen("tcp4", address)
+	if err != nil {
+		return true
+	}
+	listener.Close()
+	if ipv6Available() {
+		listener, err := net.Listen("tcp6", address)
+		if err != nil {
+			return true
+		}
+		listener.Close()
+	}
+
+	// A program listening only on the loopback doesn't prevent listening on all the interfaces on every system (e.g. macOS)
+	for _, host := range []string{"127.0.0.1", "::1"} {
+		if conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), portDialTimeout); err == nil {
+			conn.Close()
+			return true
+		}
+	}
+	return false
+}
+
+// If the machine has IPv6, the ports are then also checked on it
+var ipv6Available = sync.OnceValue(func() bool {
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+})
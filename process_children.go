@@ -0,0 +1,48 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Kill all the processes started by the app, and the ones started by them
+func killChildProcesses() {
+	killDescendants(os.Getpid())
+}
+
+func killDescendants(pid int) {
+	// pgrep exits with an error when there are no children
+	output, err := RunCommand(exec.Command("pgrep", "-P", strconv.Itoa(pid)))
+	if err != nil {
+		return
+	}
+
+	for _, field := range strings.Fields(output) {
+		child, err := strconv.Atoi(field)
+		if err != nil {
+			continue
+		}
+		// Kill the children of the child first, they would be reparented otherwise
+		killDescendants(child)
+		if process, err := os.FindProcess(child); err == nil {
+			process.Kill()
+		}
+	}
+}
+
+// If the process with the given id is running, signal 0 only checks that it exists
+func isProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	// The process exists but belongs to another user
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,56 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// The exit code of a process that is still running
+const stillActive = 259
+
+// The access right to query the exit code of a process
+const processQueryLimitedInformation = 0x1000
+
+// Kill all the processes started by the app, and the ones started by them
+func killChildProcesses() {
+	// Get the ids of the children of the app
+	output, err := RunCommand(exec.Command("powershell", "-NoProfile", "-Command",
+		"Get-CimInstance Win32_Process -Filter \"ParentProcessId="+strconv.Itoa(os.Getpid())+"\" | Select-Object -ExpandProperty ProcessId"))
+	if err != nil {
+		return
+	}
+
+	for _, field := range strings.Fields(output) {
+		child, err := strconv.Atoi(field)
+		if err != nil {
+			continue
+		}
+		// Kill the whole tree of the child
+		RunCommand(exec.Command("taskkill", "/F", "/T", "/PID", strconv.Itoa(child)))
+	}
+}
+
+// If the process with the given id is running
+func isProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		// The process doesn't exist, or belongs to another user and can't be queried
+		return errors.Is(err, syscall.ERROR_ACCESS_DENIED)
+	}
+	defer syscall.CloseHandle(handle)
+	var exitCode uint32
+	if err := syscall.GetExitCodeProcess(handle, &exitCode); err != nil {
+		return false
+	}
+	return exitCode == stillActive
+}
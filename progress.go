@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The event sent to the frontend with the structured progress of an operation, in addition to the TERMINAL_OUTPUT lines
+const progressEventName = "OPERATION_PROGRESS"
+
+// The kinds of progress events
+const (
+	progressStep      = "step"      // a new step of the operation started
+	progressWaiting   = "waiting"   // the operation is waiting for the services to be ready
+	progressImage     = "image"     // an image is being pulled
+	progressService   = "service"   // a container or kubernetes resource changed state
+	progressInfo      = "info"      // a notification from the platform cmd
+	progressWarning   = "warning"   // something unexpected that doesn't stop the operation
+	progressError     = "error"     // an error reported by the platform cmd
+	progressIdle      = "idle"      // nothing has been printed for a while
+	progressDone      = "done"      // the operation finished successfully
+	progressFailed    = "failed"    // the operation finished with an error
+	progressCancelled = "cancelled" // the operation was cancelled by the user
+)
+
+// ProgressEvent describes one update of a running operation
+type ProgressEvent struct {
+	OperationId    string    `json:"operationId"`
+	Operation      string    `json:"operation"`
+	Platform       string    `json:"platform"`
+	Kind           string    `json:"kind"`
+	Phase          string    `json:"phase"`
+	Step           int       `json:"step"`
+	TotalSteps     int       `json:"totalSteps"`
+	Percent        int       `json:"percent"`
+	Message        string    `json:"message"`
+	Image          string    `json:"image"`
+	Service        string    `json:"service"`
+	State          string    `json:"state"`
+	ElapsedSeconds int       `json:"elapsedSeconds"`
+	IdleSeconds    int       `json:"idleSeconds"`
+	Time           time.Time `json:"time"`
+}
+
+// Implemented by the drivers that know how many steps ([TASK] lines) their operations print,
+// used to compute the percentage of the progress
+type progressStepCounter interface {
+	ProgressSteps(operation string) int
+}
+
+// The time without output after which idle events are sent, so that a slow step can be told apart from a hung one
+const progressIdleAfter = 10 * time.Second
+
+var (
+	ansiEscapeRegexp = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+	// [TASK] Installing rabbitmq container on the machine
+	taggedLineRegexp = regexp.MustCompile(`^\[(TASK|WAITING|NOTIFICATION|ERROR|NEW VERSION AVAILABLE)\]\s*(.*)$`)
+	// Container env1-0-rabbitmq  Started
+	composeContainerRegexp = regexp.MustCompile(`^(?:[✔✘⠿]\s*)?Container\s+(\S+)\s+(Creating|Created|Recreate|Recreated|Starting|Started|Waiting|Healthy|Running|Stopping|Stopped|Removing|Removed|Restarting|Error)\b`)
+	// rabbitmq Pulling / rabbitmq Pulled / Pulling from epos/data-portal
+	composePullRegexp = regexp.MustCompile(`^(?:[✔✘⠿]\s*)?(\S+)\s+(Pulling|Pulled)$`)
+	pullFromRegexp    = regexp.MustCompile(`Pulling from (\S+)`)
+	// deployment.apps/gateway created / pod/gateway-7c9 condition met
+	kubernetesResourceRegexp = regexp.MustCompile(`^(\S+/\S+)\s+(created|configured|unchanged|deleted|restarted|condition met)$`)
+	// A docker layer id, not interesting for the progress
+	layerIdRegexp = regexp.MustCompile(`^[0-9a-f]{12}$`)
+)
+
+// Turns the output lines of an operation into progress events
+type progressTracker struct {
+	mu          sync.Mutex
+	operationId string
+	operation   string
+	platform    string
+	totalSteps  int
+	step        int
+	phase       string
+	started     time.Time
+	lastOutput  time.Time
+	emit        func(ProgressEvent)
+	stop        chan struct{}
+}
+
+// Create a tracker for a running operation of the given driver, events are passed to emit
+func newProgressTracker(op *operation, driver PlatformDriver, emit func(ProgressEvent)) *progressTracker {
+	totalSteps := 0
+	if counter, ok := driver.(progressStepCounter); ok {
+		totalSteps = counter.ProgressSteps(op.Kind)
+	}
+
+	now := time.Now()
+	return &progressTracker{
+		operationId: op.ID,
+		operation:   op.Kind,
+		platform:    driver.Name(),
+		totalSteps:  totalSteps,
+		phase:       "preparing",
+		started:     now,
+		lastOutput:  now,
+		emit:        emit,
+	}
+}
+
+// Start sending idle events when the operation doesn't print anything for a while
+func (t *progressTracker) start() {
+	t.stop = make(chan struct{})
+	t.send(ProgressEvent{Kind: progressStep, Message: "Preparing the " + t.operation})
+
+	go func() {
+		ticker := time.NewTicker(progressIdleAfter / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.stop:
+				return
+			case <-ticker.C:
+				t.mu.Lock()
+				idle := time.Since(t.lastOutput)
+				t.mu.Unlock()
+				if idle >= progressIdleAfter {
+					t.send(ProgressEvent{Kind: progressIdle, IdleSeconds: int(idle.Seconds()), Message: "No output for " + idle.Round(time.Second).String()})
+				}
+			}
+		}
+	}()
+}
+
+// Parse an output line and send the matching progress event, if any
+func (t *progressTracker) line(line string) {
+	t.mu.Lock()
+	t.lastOutput = time.Now()
+	t.mu.Unlock()
+
+	event, ok := parseProgressLine(line)
+	if !ok {
+		return
+	}
+
+	if event.Kind == progressStep {
+		t.mu.Lock()
+		t.step++
+		t.mu.Unlock()
+	}
+	t.send(event)
+}
+
+// Stop the tracker and send the final event
+func (t *progressTracker) finish(err error) {
+	if t.stop != nil {
+		close(t.stop)
+	}
+	if errors.Is(err, context.Canceled) {
+		t.send(ProgressEvent{Kind: progressCancelled, Message: "Cancelled"})
+		return
+	}
+	if err != nil {
+		t.send(ProgressEvent{Kind: progressFailed, Message: err.Error()})
+		return
+	}
+	t.send(ProgressEvent{Kind: progressDone, Message: "Completed"})
+}
+
+// Fill the common fields of the event and send it
+func (t *progressTracker) send(event ProgressEvent) {
+	t.mu.Lock()
+	switch event.Kind {
+	case progressStep:
+		if t.step > 0 {
+			t.phase = "running"
+		}
+	case progressWaiting:
+		t.phase = "waiting"
+	case progressDone:
+		t.phase = "done"
+	case progressFailed:
+		t.phase = "failed"
+	case progressCancelled:
+		t.phase = "cancelled"
+	}
+
+	event.OperationId = t.operationId
+	event.Operation = t.operation
+	event.Platform = t.platform
+	event.Phase = t.phase
+	event.Step = t.step
+	event.TotalSteps = t.totalSteps
+	event.Percent = t.percent(event.Kind)
+	event.ElapsedSeconds = int(time.Since(t.started).Seconds())
+	event.Time = time.Now()
+	t.mu.Unlock()
+
+	t.emit(event)
+}
+
+// The percentage of completion, -1 when it is unknown
+func (t *progressTracker) percent(kind string) int {
+	if kind == progressDone {
+		return 100
+	}
+	if t.totalSteps <= 0 {
+		return -1
+	}
+	// The last step is only complete when the operation is done
+	percent := t.step * 100 / (t.totalSteps + 1)
+	if percent > 99 {
+		percent = 99
+	}
+	return percent
+}
+
+// Convert an output line of the docker or kubernetes cmd to a progress event (without the common fields)
+func parseProgressLine(line string) (ProgressEvent, bool) {
+	line = strings.TrimSpace(ansiEscapeRegexp.ReplaceAllString(line, ""))
+	if line == "" {
+		return ProgressEvent{}, false
+	}
+
+	// The messages printed by the cmd functions (PrintTask, PrintWait, ...)
+	if match := taggedLineRegexp.FindStringSubmatch(line); match != nil {
+		message := strings.TrimSpace(match[2])
+		switch match[1] {
+		case "TASK":
+			return ProgressEvent{Kind: progressStep, Message: message}, true
+		case "WAITING":
+			return ProgressEvent{Kind: progressWaiting, Message: message}, true
+		case "ERROR":
+			return ProgressEvent{Kind: progressError, Message: message}, true
+		case "NEW VERSION AVAILABLE":
+			return ProgressEvent{Kind: progressWarning, Message: "New version available: " + message}, true
+		default:
+			return ProgressEvent{Kind: progressInfo, Message: message}, true
+		}
+	}
+
+	// The output of docker compose
+	if match := composeContainerRegexp.FindStringSubmatch(line); match != nil {
+		kind := progressService
+		if match[2] == "Error" {
+			kind = progressError
+		}
+		return ProgressEvent{Kind: kind, Service: match[1], State: strings.ToLower(match[2]), Message: line}, true
+	}
+	if match := composePullRegexp.FindStringSubmatch(line); match != nil && !layerIdRegexp.MatchString(match[1]) {
+		return ProgressEvent{Kind: progressImage, Image: match[1], State: strings.ToLower(match[2]), Message: line}, true
+	}
+	if match := pullFromRegexp.FindStringSubmatch(line); match != nil {
+		return ProgressEvent{Kind: progressImage, Image: match[1], State: "pulling", Message: line}, true
+	}
+
+	// The output of kubectl
+	if match := kubernetesResourceRegexp.FindStringSubmatch(line); match != nil {
+		return ProgressEvent{Kind: progressService, Service: match[1], State: match[2], Message: line}, true
+	}
+
+	// Anything else that looks like a warning or an error
+	lower := strings.ToLower(line)
+	if strings.HasPrefix(lower, "warn") || strings.Contains(lower, " warning") || strings.Contains(lower, "level=warn") {
+		return ProgressEvent{Kind: progressWarning, Message: line}, true
+	}
+	if strings.HasPrefix(lower, "error") || strings.Contains(lower, "level=error") {
+		return ProgressEvent{Kind: progressError, Message: line}, true
+	}
+
+	return ProgressEvent{}, false
+}
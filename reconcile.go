@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// The status of an installed environment, found by comparing the database with what is on its platform
+const (
+	environmentRunning = "running"
+	environmentStopped = "stopped"
+	// The platform can be reached but the environment is not on it anymore, it was removed outside of the app
+	environmentMissing = "missing"
+	// The docker daemon (or the podman machine) is not running, or the kubernetes cluster can't be reached
+	environmentUnreachable = "unreachable"
+	// The status could not be checked, e.g. the tools of the platform are not installed
+	environmentUnknown = "unknown"
+)
+
+// Set the status of the environments from their platforms.
+// The checks run in parallel, a kubernetes cluster that can't be reached takes a few seconds to time out.
+func reconcileEnvironments(environments []Environment) {
+	var wg sync.WaitGroup
+	for i := range environments {
+		wg.Add(1)
+		go func(environment *Environment) {
+			defer wg.Done()
+			environment.Status = environmentStatus(*environment)
+		}(&environments[i])
+	}
+	wg.Wait()
+}
+
+// Get the status of an environment on its platform
+func environmentStatus(environment Environment) string {
+	driver, err := getPlatformDriver(environment.Platform)
+	if err != nil {
+		return environmentUnknown
+	}
+	return driver.Status(environment)
+}
+
+// Remove from the database the environments that are missing from their platform, after the user confirmed it.
+// The environments whose platform can't be reached are kept, as are the ones with a running operation.
+// Returns the removed environments.
+func (a *App) PruneMissingEnvironments() ([]Environment, error) {
+	environments, err := a.store.GetEnvironments()
+	if err != nil {
+		return nil, err
+	}
+
+	// An environment being installed again is not on its platform until its containers are created
+	busy := make(map[string]bool)
+	for _, op := range a.GetRunningOperations() {
+		busy[op.EnvironmentID] = true
+	}
+
+	// Check the status again, the environments may have come back since they were listed
+	reconcileEnvironments(environments)
+
+	pruned := []Environment{}
+	for _, environment := range environments {
+		if environment.Status != environmentMissing || busy[environment.ID] {
+			continue
+		}
+		if err := a.store.DeleteEnvironment(environment.ID); err != nil {
+			return pruned, fmt.Errorf("error removing the environment %s %s: %w", environment.EnvironmentSetup.Name, environment.EnvironmentSetup.Version, err)
+		}
+		driver, err := getPlatformDriver(environment.Platform)
+		if err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, environmentForFrontend(environment, driver))
+	}
+	return pruned, nil
+}
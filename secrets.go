@@ -0,0 +1,195 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// The value shown instead of the secret variables outside of the backend
+const secretMask = "********"
+
+// The variables whose names end like this hold passwords or keys
+var secretVariableRegexp = regexp.MustCompile(`(?i)(PASSWORD|PASSWD|PWD|SECRET|TOKEN|_KEY)$`)
+
+// The secret variables that can't be recognized by their name
+var secretVariableNames = map[string]bool{
+	"INGESTOR_HASH": true,
+}
+
+// Check if the variable holds a secret that must not be stored or shown in clear
+func isSecretVariable(name string) bool {
+	return secretVariableNames[name] || secretVariableRegexp.MatchString(name)
+}
+
+// Return a copy of the sections with the secret values replaced by the mask, empty values are left empty
+func maskSecrets(sections []Section) []Section {
+	masked := copySections(sections)
+	for _, section := range masked {
+		for name, value := range section.Variables {
+			if value != "" && isSecretVariable(name) {
+				section.Variables[name] = secretMask
+			}
+		}
+	}
+	return masked
+}
+
+// Replace the masked secrets of sections, as sent back by the frontend, with the values in stored
+func unmaskSecrets(sections []Section, stored []Section) {
+	values := make(map[string]string)
+	for _, section := range stored {
+		for name, value := range section.Variables {
+			values[name] = value
+		}
+	}
+	for _, section := range sections {
+		for name, value := range section.Variables {
+			if value == secretMask && isSecretVariable(name) {
+				section.Variables[name] = values[name]
+			}
+		}
+	}
+}
+
+// Move the secret values out of the sections, returns the sections without them and the secrets by name
+func extractSecrets(sections []Section) ([]Section, map[string]string) {
+	public := copySections(sections)
+	secrets := make(map[string]string)
+	for _, section := range public {
+		for name, value := range section.Variables {
+			if isSecretVariable(name) {
+				secrets[name] = value
+				section.Variables[name] = ""
+			}
+		}
+	}
+	return public, secrets
+}
+
+// Put the secret values back in the sections returned by extractSecrets
+func restoreSecrets(sections []Section, secrets map[string]string) {
+	for _, section := range sections {
+		for name := range section.Variables {
+			if value, ok := secrets[name]; ok {
+				section.Variables[name] = value
+			}
+		}
+	}
+}
+
+// Replace the secret values found in a line of output with the mask
+func redactSecrets(line string, secrets []string) string {
+	for _, secret := range secrets {
+		line = strings.ReplaceAll(line, secret, secretMask)
+	}
+	return line
+}
+
+// The values of the secret variables of the sections, the short ones are ignored as they would mask unrelated text
+func secretValuesOf(sections []Section) []string {
+	var values []string
+	for _, section := range sections {
+		for name, value := range section.Variables {
+			if len(value) >= 4 && isSecretVariable(name) {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// Encrypts the secrets stored in the database with a key kept in a file readable only by the user
+type secretBox struct {
+	aead cipher.AEAD
+}
+
+// Open the box with the key at keyPath, the key is generated the first time
+func newSecretBox(keyPath string) (*secretBox, error) {
+	key, err := os.ReadFile(keyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		// O_EXCL: don't overwrite a key created in the meantime by another instance of the app
+		file, err := os.OpenFile(keyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			return newSecretBox(keyPath)
+		}
+		if err != nil {
+			return nil, err
+		}
+		_, err = file.Write(key)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid secrets key %s", keyPath)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &secretBox{aead: aead}, nil
+}
+
+// Encrypt the secrets, an empty map is sealed as an empty string
+func (b *secretBox) seal(secrets map[string]string) (string, error) {
+	if len(secrets) == 0 {
+		return "", nil
+	}
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt secrets encrypted by seal
+func (b *secretBox) open(sealed string) (map[string]string, error) {
+	secrets := make(map[string]string)
+	if sealed == "" {
+		return secrets, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < b.aead.NonceSize() {
+		return nil, errors.New("invalid encrypted secrets")
+	}
+	plaintext, err := b.aead.Open(nil, data[:b.aead.NonceSize()], data[b.aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt the secrets, the key may have changed: %w", err)
+	}
+
+	err = json.Unmarshal(plaintext, &secrets)
+	return secrets, err
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMaskSecrets(t *testing.T) {
+	tests := []struct {
+		name      string
+		variables map[string]string
+		want      map[string]string
+	}{
+		{
+			name:      "secrets by name",
+			variables: map[string]string{"POSTGRESQL_PASSWORD": "pass", "MONITORING_PWD": "pwd", "SECURITY_KEY": "key", "INGESTOR_HASH": "abc"},
+			want:      map[string]string{"POSTGRESQL_PASSWORD": secretMask, "MONITORING_PWD": secretMask, "SECURITY_KEY": secretMask, "INGESTOR_HASH": secretMask},
+		},
+		{
+			name:      "other variables",
+			variables: map[string]string{"API_PORT": "33000", "POSTGRES_USER": "postgres", "KEYCLOAK_URL": "http://host"},
+			want:      map[string]string{"API_PORT": "33000", "POSTGRES_USER": "postgres", "KEYCLOAK_URL": "http://host"},
+		},
+		{
+			name:      "empty secret",
+			variables: map[string]string{"REGISTRY_PASSWORD": ""},
+			want:      map[string]string{"REGISTRY_PASSWORD": ""},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sections := []Section{{Name: "Section", Variables: test.variables}}
+			original := copySections(sections)
+
+			masked := maskSecrets(sections)
+			if !reflect.DeepEqual(masked[0].Variables, test.want) {
+				t.Errorf("masked = %v, want %v", masked[0].Variables, test.want)
+			}
+			// The sections given are left as they are
+			if !reflect.DeepEqual(sections, original) {
+				t.Errorf("maskSecrets changed its argument: %v", sections)
+			}
+		})
+	}
+}
+
+func TestUnmaskSecrets(t *testing.T) {
+	stored := []Section{
+		{Name: "Database", Variables: map[string]string{"POSTGRESQL_PASSWORD": "stored", "POSTGRES_USER": "postgres"}},
+		{Name: "Security", Variables: map[string]string{"SECURITY_KEY": "stored-key"}},
+	}
+
+	tests := []struct {
+		name      string
+		variables map[string]string
+		want      map[string]string
+	}{
+		{
+			name:      "masked secrets get the stored values",
+			variables: map[string]string{"POSTGRESQL_PASSWORD": secretMask, "SECURITY_KEY": secretMask},
+			want:      map[string]string{"POSTGRESQL_PASSWORD": "stored", "SECURITY_KEY": "stored-key"},
+		},
+		{
+			name:      "changed secret is kept",
+			variables: map[string]string{"POSTGRESQL_PASSWORD": "new"},
+			want:      map[string]string{"POSTGRESQL_PASSWORD": "new"},
+		},
+		{
+			name:      "mask in a variable that is not a secret is a value",
+			variables: map[string]string{"POSTGRES_USER": secretMask},
+			want:      map[string]string{"POSTGRES_USER": secretMask},
+		},
+		{
+			name:      "secret that was not stored",
+			variables: map[string]string{"MONITORING_PWD": secretMask},
+			want:      map[string]string{"MONITORING_PWD": ""},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sections := []Section{{Name: "Edited", Variables: test.variables}}
+			unmaskSecrets(sections, stored)
+			if !reflect.DeepEqual(sections[0].Variables, test.want) {
+				t.Errorf("unmasked = %v, want %v", sections[0].Variables, test.want)
+			}
+		})
+	}
+}
+
+// The values masked for the frontend are restored when they come back unchanged
+func TestMaskUnmaskSecretsRoundTrip(t *testing.T) {
+	a := &App{store: newMemoryEnvironmentStore()}
+	defaults, err := a.ReadEnvVariables("docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	masked := maskSecrets(defaults)
+	unmaskSecrets(masked, defaults)
+	if !reflect.DeepEqual(masked, defaults) {
+		t.Error("the secrets of the docker defaults were not restored")
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// The event sent to the frontend for each line of the logs of a service, with the line and the id of the stream
+const serviceLogsEventName = "SERVICE_LOGS"
+
+// The event sent to the frontend when a stream of logs ends, with the id of the stream and the error, empty if none
+const serviceLogsEndEventName = "SERVICE_LOGS_END"
+
+// The streams of logs being sent to the frontend, by id
+type logStreamRegistry struct {
+	mu      sync.Mutex
+	streams map[string]context.CancelFunc
+}
+
+// Start sending the logs of a service of an installed environment to the frontend as SERVICE_LOGS events,
+// the service is one of those listed by GetEnvironmentStatus. With follow the new lines keep being sent until
+// StopServiceLogs is called, tail limits the lines already written (all of them if 0). Returns the id of the stream.
+func (a *App) StreamServiceLogs(envId, service string, follow bool, tail int) (string, error) {
+	environment, err := a.store.GetEnvironment(envId)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithCancel(a.ctx)
+	id := newOperationId()
+	a.logStreams.mu.Lock()
+	if a.logStreams.streams == nil {
+		a.logStreams.streams = make(map[string]context.CancelFunc)
+	}
+	a.logStreams.streams[id] = cancel
+	a.logStreams.mu.Unlock()
+
+	// The lines are sent like the output of an operation, with the secrets of the environment masked
+	output := newOperationOutput(id, func(_ string, data ...interface{}) {
+		a.emit(serviceLogsEventName, data...)
+	}, nil, nil)
+	output.secrets = secretValuesOf(environment.Variables)
+
+	go func() {
+		err := writeServiceLogs(ctx, environment, service, follow, tail, output)
+		output.Close()
+
+		a.logStreams.mu.Lock()
+		delete(a.logStreams.streams, id)
+		a.logStreams.mu.Unlock()
+		cancel()
+
+		// Stopping the stream is not an error
+		message := ""
+		if err != nil && ctx.Err() == nil {
+			message = err.Error()
+		}
+		a.emit(serviceLogsEndEventName, id, message)
+	}()
+	return id, nil
+}
+
+// Stop a stream of logs started by StreamServiceLogs
+func (a *App) StopServiceLogs(id string) error {
+	a.logStreams.mu.Lock()
+	cancel, ok := a.logStreams.streams[id]
+	a.logStreams.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("log stream not found: %s", id)
+	}
+
+	cancel()
+	return nil
+}
+
+// Write the logs of a service of the environment to out, until they end or ctx is done
+func writeServiceLogs(ctx context.Context, environment Environment, service string, follow bool, tail int, out io.Writer) error {
+	driver, err := getPlatformDriver(environment.Platform)
+	if err != nil {
+		return err
+	}
+	cmd, err := driver.LogsCommand(ctx, environment, service, follow, tail)
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	hideConsoleWindow(cmd)
+	return cmd.Run()
+}
+
+// Ask where to save the logs of all the services of an installed environment and write them there in a zip file.
+// Returns the path of the file, empty if the dialog was cancelled.
+func (a *App) DownloadLogsBundle(id string) (string, error) {
+	environment, err := a.store.GetEnvironment(id)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := wailsRuntime.SaveFileDialog(a.ctx, wailsRuntime.SaveDialogOptions{
+		Title:           "Save the logs of the environment",
+		DefaultFilename: environment.EnvironmentSetup.Name + "-" + environment.EnvironmentSetup.Version + "-logs.zip",
+		Filters:         []wailsRuntime.FileFilter{{DisplayName: "Zip file (*.zip)", Pattern: "*.zip"}},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+
+	return path, writeLogsBundle(a.ctx, path, environment)
+}
+
+// Write a zip file at path with the logs of every service of the environment and its variables, the secrets masked
+func writeLogsBundle(ctx context.Context, path string, environment Environment) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	bundle := zip.NewWriter(file)
+	if err := addLogsToZip(ctx, bundle, "", environment); err != nil {
+		bundle.Close()
+		return err
+	}
+	if err := bundle.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Add the variables and the logs of every service of the environment to a zip, in the folder dir (e.g. "env1-1.0/").
+// A service whose logs can't be read gets the error in its file instead.
+func addLogsToZip(ctx context.Context, bundle *zip.Writer, dir string, environment Environment) error {
+	if err := addZipFile(bundle, dir+"env.env", variablesToBinary(maskSecrets(environment.Variables))); err != nil {
+		return err
+	}
+
+	driver, err := getPlatformDriver(environment.Platform)
+	if err != nil {
+		return err
+	}
+	services, err := driver.Services(environment)
+	if err != nil {
+		// The variables are still worth having
+		return addZipFile(bundle, dir+"services-error.txt", []byte(err.Error()+"\n"))
+	}
+
+	secrets := secretValuesOf(environment.Variables)
+	for _, service := range services {
+		entry, err := bundle.CreateHeader(&zip.FileHeader{Name: dir + "logs/" + service.Name + ".log", Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		output := newOperationOutput("", func(_ string, data ...interface{}) {
+			fmt.Fprintln(entry, data[0])
+		}, nil, nil)
+		output.secrets = secrets
+		err = writeServiceLogs(ctx, environment, service.Name, false, 0, output)
+		output.Close()
+		if err != nil {
+			fmt.Fprintf(entry, "Error reading the logs: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// Add a file with the given content to a zip
+func addZipFile(bundle *zip.Writer, name string, data []byte) error {
+	entry, err := bundle.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = entry.Write(data)
+	return err
+}
@@ -0,0 +1,9 @@
+//go:build !cgo
+// +build !cgo
+
+package main
+
+// The sqlite driver needs cgo, without it the database can't be opened and no statement is refused
+func isUniqueConstraintError(err error) bool {
+	return false
+}
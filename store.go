@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// Returned by the stores when the requested environment does not exist
+var errEnvironmentNotFound = errors.New("environment not found")
+
+// Returned by the stores when saving an environment with the same name, version, platform and context as another one
+var errEnvironmentExists = errors.New("environment already installed")
+
+// Returned by the stores when the requested operation does not exist
+var errOperationNotFound = errors.New("operation not found")
+
+// Returned by the stores when reserving a port already reserved by another environment
+var errPortReserved = errors.New("port already reserved")
+
+// The status of an operation in the history
+const (
+	operationRunning     = "running"
+	operationSucceeded   = "succeeded"
+	operationFailed      = "failed"
+	operationCancelled   = "cancelled"
+	operationInterrupted = "interrupted" // the app was closed while the operation was running
+)
+
+// OperationRecord is an install, populate or delete in the history of an environment
+type OperationRecord struct {
+	ID            string    `json:"id"`
+	Kind          string    `json:"kind"`
+	EnvironmentID string    `json:"environmentId"`
+	Platform      string    `json:"platform"`
+	Name          string    `json:"name"`
+	Version       string    `json:"version"`
+	Context       string    `json:"context"`
+	StartedAt     time.Time `json:"startedAt"`
+	// Zero while the operation is running
+	EndedAt time.Time `json:"endedAt"`
+	Status  string    `json:"status"`
+	// The error returned by the operation if it failed
+	Error string `json:"error"`
+	// The process of the app (window or CLI) running the operation
+	PID int `json:"pid"`
+}
+
+// PortReservation is a port of the machine published by an environment, no other environment can use it
+type PortReservation struct {
+	Port          int    `json:"port"`
+	EnvironmentID string `json:"environmentId"`
+	// The variable of the environment with the port (e.g. API_PORT)
+	Variable string `json:"variable"`
+}
+
+// EnvironmentStore persists the installed environments and the user settings of the app
+type EnvironmentStore interface {
+	// Get all the installed environments
+	GetEnvironments() ([]Environment, error)
+	// Get the environment with the given id
+	GetEnvironment(id string) (Environment, error)
+	// Get the environment with the given name, version, platform and context (only used for kubernetes)
+	FindEnvironment(name, version, platform, context string) (Environment, error)
+	// Insert the environment, or replace the one with the same id. Returns errEnvironmentExists if
+	// another environment has the same name, version, platform and context
+	SaveEnvironment(environment Environment) error
+	// Delete the environment with the given id and release its ports
+	DeleteEnvironment(id string) error
+
+	// Get the ports reserved by the environments, sorted by port
+	GetPortReservations() ([]PortReservation, error)
+	// Replace the ports reserved by the environment with the given ones, none releases them. Returns errPortReserved
+	// if another environment reserved one of them, the ports of the environment are then left as they were.
+	ReservePorts(environmentId string, reservations []PortReservation) error
+
+	// Get the folder where the executables of a platform are located, empty if it was never specified
+	GetPlatformPath(platform string) (string, error)
+	// Save the folder where the executables of a platform are located
+	SetPlatformPath(platform, path string) error
+
+	// Get the value of a setting of the app, empty if it was never set
+	GetSetting(key string) (string, error)
+	// Save the value of a setting of the app
+	SetSetting(key, value string) error
+
+	// Add an operation to the history
+	AddOperation(record OperationRecord) error
+	// Append text to the log of the operation
+	AppendOperationLog(id, text string) error
+	// Save the end time, the status and the error of the operation
+	FinishOperation(id string, endedAt time.Time, status, errorMessage string) error
+	// Get the operations of the environment with the given id, the most recent first
+	GetOperations(environmentId string) ([]OperationRecord, error)
+	// Get the last operations of all the environments, including the ones that were deleted or never installed
+	// (e.g. a failed install), the most recent first
+	GetRecentOperations(limit int) ([]OperationRecord, error)
+	// Get the full log of the operation
+	GetOperationLog(id string) (string, error)
+	// Mark the running operations of the processes that are no longer alive as interrupted, ended at endedAt.
+	// Returns how many there were.
+	InterruptRunningOperations(alive func(pid int) bool, endedAt time.Time) (int, error)
+
+	Close() error
+}
+
+// Return a copy of the sections that doesn't share the variables maps with the original
+func copySections(sections []Section) []Section {
+	if sections == nil {
+		return nil
+	}
+	copied := make([]Section, len(sections))
+	for i, section := range sections {
+		variables := make(map[string]string, len(section.Variables))
+		for key, value := range section.Variables {
+			variables[key] = value
+		}
+		copied[i] = Section{
+			Name:        section.Name,
+			Description: section.Description,
+			Variables:   variables,
+			Details:     append([]VariableDetails(nil), section.Details...),
+		}
+	}
+	return copied
+}
+
+// The context that makes an environment unique with its name, version and platform, only kubernetes environments have one
+func environmentContext(platform, context string) string {
+	if platform != "kubernetes" {
+		return ""
+	}
+	return context
+}
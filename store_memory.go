@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnvironmentStore that keeps everything in memory, used when the app must not touch the user's folders (e.g. in tests)
+type memoryEnvironmentStore struct {
+	mu            sync.Mutex
+	environments  []Environment
+	platformPaths map[string]string
+	settings      map[string]string
+	operations    []OperationRecord
+	operationLogs map[string]*strings.Builder
+	reservations  []PortReservation
+}
+
+func newMemoryEnvironmentStore() *memoryEnvironmentStore {
+	return &memoryEnvironmentStore{
+		platformPaths: make(map[string]string),
+		settings:      make(map[string]string),
+		operationLogs: make(map[string]*strings.Builder),
+	}
+}
+
+// Return the index of the environment with the given id, -1 if there is none
+func (s *memoryEnvironmentStore) indexOf(id string) int {
+	for i, environment := range s.environments {
+		if environment.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Return the index of the environment with the given name, version, platform and context, -1 if there is none
+func (s *memoryEnvironmentStore) indexOfKey(name, version, platform, context string) int {
+	for i, environment := range s.environments {
+		if environment.EnvironmentSetup.Name == name && environment.EnvironmentSetup.Version == version && environment.Platform == platform &&
+			environment.EnvironmentSetup.Context == environmentContext(platform, context) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *memoryEnvironmentStore) GetEnvironments() ([]Environment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var environments []Environment
+	for _, environment := range s.environments {
+		environment.Variables = copySections(environment.Variables)
+		environments = append(environments, environment)
+	}
+	return environments, nil
+}
+
+func (s *memoryEnvironmentStore) GetEnvironment(id string) (Environment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.indexOf(id)
+	if i < 0 {
+		return Environment{}, fmt.Errorf("%w: %s", errEnvironmentNotFound, id)
+	}
+	environment := s.environments[i]
+	environment.Variables = copySections(environment.Variables)
+	return environment, nil
+}
+
+func (s *memoryEnvironmentStore) FindEnvironment(name, version, platform, context string) (Environment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.indexOfKey(name, version, platform, context)
+	if i < 0 {
+		return Environment{}, fmt.Errorf("%w: %s %s", errEnvironmentNotFound, name, version)
+	}
+	environment := s.environments[i]
+	environment.Variables = copySections(environment.Variables)
+	return environment, nil
+}
+
+func (s *memoryEnvironmentStore) SaveEnvironment(environment Environment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	environment.Variables = copySections(environment.Variables)
+	environment.EnvironmentSetup.Context = environmentContext(environment.Platform, environment.EnvironmentSetup.Context)
+	setup := environment.EnvironmentSetup
+	if j := s.indexOfKey(setup.Name, setup.Version, environment.Platform, setup.Context); j >= 0 && s.environments[j].ID != environment.ID {
+		return fmt.Errorf("%w: %s %s", errEnvironmentExists, setup.Name, setup.Version)
+	}
+
+	i := s.indexOf(environment.ID)
+	if i < 0 {
+		s.environments = append(s.environments, environment)
+	} else {
+		s.environments[i] = environment
+	}
+	return nil
+}
+
+func (s *memoryEnvironmentStore) DeleteEnvironment(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.indexOf(id); i >= 0 {
+		s.environments = append(s.environments[:i], s.environments[i+1:]...)
+	}
+	s.reservations = s.reservationsExcept(id)
+	return nil
+}
+
+func (s *memoryEnvironmentStore) GetPortReservations() ([]PortReservation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reservations := append([]PortReservation{}, s.reservations...)
+	sort.Slice(reservations, func(i, j int) bool {
+		return reservations[i].Port < reservations[j].Port
+	})
+	return reservations, nil
+}
+
+func (s *memoryEnvironmentStore) ReservePorts(environmentId string, reservations []PortReservation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.reservationsExcept(environmentId)
+	reserved := make(map[int]bool)
+	for _, reservation := range kept {
+		reserved[reservation.Port] = true
+	}
+	for _, reservation := range reservations {
+		if reserved[reservation.Port] {
+			return fmt.Errorf("%w: %d", errPortReserved, reservation.Port)
+		}
+		reserved[reservation.Port] = true
+		reservation.EnvironmentID = environmentId
+		kept = append(kept, reservation)
+	}
+	s.reservations = kept
+	return nil
+}
+
+// The reservations of the other environments
+func (s *memoryEnvironmentStore) reservationsExcept(environmentId string) []PortReservation {
+	var kept []PortReservation
+	for _, reservation := range s.reservations {
+		if reservation.EnvironmentID != environmentId {
+			kept = append(kept, reservation)
+		}
+	}
+	return kept
+}
+
+func (s *memoryEnvironmentStore) GetPlatformPath(platform string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.platformPaths[platform], nil
+}
+
+func (s *memoryEnvironmentStore) SetPlatformPath(platform, path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.platformPaths[platform] = path
+	return nil
+}
+
+func (s *memoryEnvironmentStore) GetSetting(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.settings[key], nil
+}
+
+func (s *memoryEnvironmentStore) SetSetting(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.settings[key] = value
+	return nil
+}
+
+func (s *memoryEnvironmentStore) AddOperation(record OperationRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.operationLogs[record.ID]; ok {
+		return fmt.Errorf("operation already exists: %s", record.ID)
+	}
+	s.operations = append(s.operations, record)
+	s.operationLogs[record.ID] = &strings.Builder{}
+	return nil
+}
+
+func (s *memoryEnvironmentStore) AppendOperationLog(id, text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log, ok := s.operationLogs[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", errOperationNotFound, id)
+	}
+	log.WriteString(text)
+	return nil
+}
+
+func (s *memoryEnvironmentStore) FinishOperation(id string, endedAt time.Time, status, errorMessage string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.operations {
+		if s.operations[i].ID == id {
+			s.operations[i].EndedAt = endedAt
+			s.operations[i].Status = status
+			s.operations[i].Error = errorMessage
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", errOperationNotFound, id)
+}
+
+func (s *memoryEnvironmentStore) GetOperations(environmentId string) ([]OperationRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := []OperationRecord{}
+	for _, record := range s.operations {
+		if record.EnvironmentID == environmentId {
+			records = append(records, record)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].StartedAt.After(records[j].StartedAt)
+	})
+	return records, nil
+}
+
+func (s *memoryEnvironmentStore) GetRecentOperations(limit int) ([]OperationRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := append([]OperationRecord{}, s.operations...)
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].StartedAt.After(records[j].StartedAt)
+	})
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+func (s *memoryEnvironmentStore) GetOperationLog(id string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log, ok := s.operationLogs[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", errOperationNotFound, id)
+	}
+	return log.String(), nil
+}
+
+func (s *memoryEnvironmentStore) InterruptRunningOperations(alive func(pid int) bool, endedAt time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	interrupted := 0
+	for i := range s.operations {
+		if s.operations[i].Status == operationRunning && !alive(s.operations[i].PID) {
+			s.operations[i].Status = operationInterrupted
+			s.operations[i].EndedAt = endedAt
+			interrupted++
+		}
+	}
+	return interrupted, nil
+}
+
+func (s *memoryEnvironmentStore) Close() error {
+	return nil
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// EnvironmentStore backed by the SQLite database in the app folder
+type sqliteEnvironmentStore struct {
+	db *sql.DB
+	// Encrypts the secret variables, with a key stored next to the database
+	secrets *secretBox
+}
+
+// The file next to the database with the key of the secret variables
+const secretsKeyFileName = "secrets.key"
+
+// Open the SQLite database at dbPath and bring its schema up to date
+func newSQLiteEnvironmentStore(dbPath string) (*sqliteEnvironmentStore, error) {
+	// Wait for the lock instead of failing when two operations write at the same time
+	db, err := sql.Open("sqlite3", dbPath+"?_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+
+	err = migrateDatabase(db, dbPath)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	secrets, err := newSecretBox(filepath.Join(filepath.Dir(dbPath), secretsKeyFileName))
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error opening the secrets key: %w", err)
+	}
+
+	s := &sqliteEnvironmentStore{db: db, secrets: secrets}
+	err = s.encryptPlaintextSecrets()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Encrypt the secrets of the environments saved by the versions of the app that stored them in clear
+func (s *sqliteEnvironmentStore) encryptPlaintextSecrets() error {
+	environments, err := s.GetEnvironments()
+	if err != nil {
+		return err
+	}
+
+	for _, environment := range environments {
+		var variables string
+		err := s.db.QueryRow("SELECT variables FROM environments WHERE id = ?", environment.ID).Scan(&variables)
+		if err != nil {
+			return err
+		}
+		var sections []Section
+		if err := json.Unmarshal([]byte(variables), &sections); err != nil {
+			return err
+		}
+
+		// Saving the environment moves the secrets to the encrypted column
+		_, secrets := extractSecrets(sections)
+		for _, value := range secrets {
+			if value != "" {
+				if err := s.SaveEnvironment(environment); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (s *sqliteEnvironmentStore) GetEnvironments() ([]Environment, error) {
+	// Query the database for all the environments
+	rows, err := s.db.Query("SELECT id, name, version, platform, variables, secrets, context, host, apiGateway, dataPortal FROM environments")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var environments []Environment
+	for rows.Next() {
+		environment, err := s.scanEnvironment(rows)
+		if err != nil {
+			return nil, err
+		}
+		environments = append(environments, environment)
+	}
+
+	return environments, rows.Err()
+}
+
+func (s *sqliteEnvironmentStore) GetEnvironment(id string) (Environment, error) {
+	row := s.db.QueryRow("SELECT id, name, version, platform, variables, secrets, context, host, apiGateway, dataPortal FROM environments WHERE id = ?", id)
+	environment, err := s.scanEnvironment(row)
+	if err == sql.ErrNoRows {
+		return Environment{}, fmt.Errorf("%w: %s", errEnvironmentNotFound, id)
+	}
+	return environment, err
+}
+
+func (s *sqliteEnvironmentStore) FindEnvironment(name, version, platform, context string) (Environment, error) {
+	row := s.db.QueryRow("SELECT id, name, version, platform, variables, secrets, context, host, apiGateway, dataPortal FROM environments WHERE name = ? AND version = ? AND platform = ? AND context = ?",
+		name, version, platform, environmentContext(platform, context))
+	environment, err := s.scanEnvironment(row)
+	if err == sql.ErrNoRows {
+		return Environment{}, fmt.Errorf("%w: %s %s", errEnvironmentNotFound, name, version)
+	}
+	return environment, err
+}
+
+// Read an environment from a row with the columns id, name, version, platform, variables, secrets, context, host, apiGateway, dataPortal
+func (s *sqliteEnvironmentStore) scanEnvironment(row interface{ Scan(dest ...any) error }) (Environment, error) {
+	var id, name, version, platform, variables, sealedSecrets, context, host, apiGateway, dataPortal string
+	err := row.Scan(&id, &name, &version, &platform, &variables, &sealedSecrets, &context, &host, &apiGateway, &dataPortal)
+	if err != nil {
+		return Environment{}, err
+	}
+
+	// Convert the variables to a slice of Section
+	var sections []Section
+	err = json.Unmarshal([]byte(variables), &sections)
+	if err != nil {
+		return Environment{}, err
+	}
+
+	// Put the decrypted secrets back in the variables
+	secrets, err := s.secrets.open(sealedSecrets)
+	if err != nil {
+		return Environment{}, fmt.Errorf("environment %s %s: %w", name, version, err)
+	}
+	restoreSecrets(sections, secrets)
+
+	return Environment{
+		ID:               id,
+		Platform:         platform,
+		EnvironmentSetup: EnvironmentSetup{Name: name, Version: version, Context: context, Host: host},
+		Variables:        sections,
+		AccessPoints:     EposAccessPoints{ApiGateway: apiGateway, DataPortal: dataPortal},
+	}, nil
+}
+
+func (s *sqliteEnvironmentStore) SaveEnvironment(environment Environment) error {
+	// Convert the variables to a JSON string, without the secrets that are saved encrypted
+	variables, secrets := extractSecrets(environment.Variables)
+	variablesJson, err := json.Marshal(variables)
+	if err != nil {
+		return err
+	}
+	sealedSecrets, err := s.secrets.seal(secrets)
+	if err != nil {
+		return err
+	}
+
+	// Upsert the environment into the database, the unique constraint refuses a second environment with the same name
+	_, err = s.db.Exec(`INSERT INTO environments(id, name, version, platform, dataPortal, apiGateway, variables, secrets, context, host) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET name = excluded.name, version = excluded.version, platform = excluded.platform, dataPortal = excluded.dataPortal,
+		apiGateway = excluded.apiGateway, variables = excluded.variables, secrets = excluded.secrets, context = excluded.context, host = excluded.host`,
+		environment.ID,
+		environment.EnvironmentSetup.Name,
+		environment.EnvironmentSetup.Version,
+		environment.Platform,
+		environment.AccessPoints.DataPortal,
+		environment.AccessPoints.ApiGateway,
+		string(variablesJson),
+		sealedSecrets,
+		environmentContext(environment.Platform, environment.EnvironmentSetup.Context),
+		environment.EnvironmentSetup.Host,
+	)
+	if isUniqueConstraintError(err) {
+		return fmt.Errorf("%w: %s %s", errEnvironmentExists, environment.EnvironmentSetup.Name, environment.EnvironmentSetup.Version)
+	}
+	return err
+}
+
+func (s *sqliteEnvironmentStore) DeleteEnvironment(id string) error {
+	// The ports are released with the environment, or neither of them is deleted
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op if the transaction has been committed
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM environments WHERE id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM port_reservations WHERE environmentId = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteEnvironmentStore) GetPortReservations() ([]PortReservation, error) {
+	rows, err := s.db.Query("SELECT port, environmentId, variable FROM port_reservations ORDER BY port")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reservations := []PortReservation{}
+	for rows.Next() {
+		var reservation PortReservation
+		if err := rows.Scan(&reservation.Port, &reservation.EnvironmentID, &reservation.Variable); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+	return reservations, rows.Err()
+}
+
+func (s *sqliteEnvironmentStore) ReservePorts(environmentId string, reservations []PortReservation) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op if the transaction has been committed
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM port_reservations WHERE environmentId = ?", environmentId); err != nil {
+		return err
+	}
+	for _, reservation := range reservations {
+		_, err := tx.Exec("INSERT INTO port_reservations(port, environmentId, variable) VALUES(?, ?, ?)", reservation.Port, environmentId, reservation.Variable)
+		if isUniqueConstraintError(err) {
+			return fmt.Errorf("%w: %d", errPortReserved, reservation.Port)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteEnvironmentStore) GetPlatformPath(platform string) (string, error) {
+	var path string
+	err := s.db.QueryRow("SELECT path FROM platform_paths WHERE platform = ?", platform).Scan(&path)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return path, err
+}
+
+func (s *sqliteEnvironmentStore) SetPlatformPath(platform, path string) error {
+	_, err := s.db.Exec("INSERT OR REPLACE INTO platform_paths(platform, path) VALUES(?, ?)", platform, path)
+	return err
+}
+
+func (s *sqliteEnvironmentStore) GetSetting(key string) (string, error) {
+	var value string
+	err := s.db.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+func (s *sqliteEnvironmentStore) SetSetting(key, value string) error {
+	_, err := s.db.Exec("INSERT OR REPLACE INTO settings(key, value) VALUES(?, ?)", key, value)
+	return err
+}
+
+func (s *sqliteEnvironmentStore) AddOperation(record OperationRecord) error {
+	_, err := s.db.Exec("INSERT INTO operations(id, kind, environmentId, name, version, platform, context, startedAt, endedAt, status, error, pid) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		record.ID,
+		record.Kind,
+		record.EnvironmentID,
+		record.Name,
+		record.Version,
+		record.Platform,
+		record.Context,
+		formatTime(record.StartedAt),
+		formatTime(record.EndedAt),
+		record.Status,
+		record.Error,
+		record.PID,
+	)
+	return err
+}
+
+func (s *sqliteEnvironmentStore) AppendOperationLog(id, text string) error {
+	// Each append is a new chunk, so that the log is never rewritten
+	_, err := s.db.Exec("INSERT INTO operation_logs(operationId, chunk, text) VALUES(?, (SELECT COALESCE(MAX(chunk), -1) + 1 FROM operation_logs WHERE operationId = ?), ?)", id, id, text)
+	return err
+}
+
+func (s *sqliteEnvironmentStore) FinishOperation(id string, endedAt time.Time, status, errorMessage string) error {
+	result, err := s.db.Exec("UPDATE operations SET endedAt = ?, status = ?, error = ? WHERE id = ?", formatTime(endedAt), status, errorMessage, id)
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return fmt.Errorf("%w: %s", errOperationNotFound, id)
+	}
+	return nil
+}
+
+func (s *sqliteEnvironmentStore) GetOperations(environmentId string) ([]OperationRecord, error) {
+	return s.queryOperations("SELECT id, kind, environmentId, name, version, platform, context, startedAt, endedAt, status, error, pid FROM operations WHERE environmentId = ? ORDER BY startedAt DESC", environmentId)
+}
+
+func (s *sqliteEnvironmentStore) GetRecentOperations(limit int) ([]OperationRecord, error) {
+	return s.queryOperations("SELECT id, kind, environmentId, name, version, platform, context, startedAt, endedAt, status, error, pid FROM operations ORDER BY startedAt DESC LIMIT ?", limit)
+}
+
+// Read the operations returned by a query on the columns id, kind, environmentId, name, version, platform, context,
+// startedAt, endedAt, status, error
+func (s *sqliteEnvironmentStore) queryOperations(query string, args ...any) ([]OperationRecord, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []OperationRecord{}
+	for rows.Next() {
+		var record OperationRecord
+		var startedAt, endedAt string
+		err := rows.Scan(&record.ID, &record.Kind, &record.EnvironmentID, &record.Name, &record.Version, &record.Platform, &record.Context, &startedAt, &endedAt, &record.Status, &record.Error, &record.PID)
+		if err != nil {
+			return nil, err
+		}
+		record.StartedAt = parseTime(startedAt)
+		record.EndedAt = parseTime(endedAt)
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+func (s *sqliteEnvironmentStore) GetOperationLog(id string) (string, error) {
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM operations WHERE id = ?)", id).Scan(&exists)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", fmt.Errorf("%w: %s", errOperationNotFound, id)
+	}
+
+	rows, err := s.db.Query("SELECT text FROM operation_logs WHERE operationId = ? ORDER BY chunk", id)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var log strings.Builder
+	for rows.Next() {
+		var text string
+		if err := rows.Scan(&text); err != nil {
+			return "", err
+		}
+		log.WriteString(text)
+	}
+
+	return log.String(), rows.Err()
+}
+
+func (s *sqliteEnvironmentStore) InterruptRunningOperations(alive func(pid int) bool, endedAt time.Time) (int, error) {
+	running, err := s.queryOperations("SELECT id, kind, environmentId, name, version, platform, context, startedAt, endedAt, status, error, pid FROM operations WHERE status = ?", operationRunning)
+	if err != nil {
+		return 0, err
+	}
+
+	interrupted := 0
+	for _, record := range running {
+		if alive(record.PID) {
+			continue
+		}
+		// The status is checked again, the process may have finished the operation in the meantime
+		result, err := s.db.Exec("UPDATE operations SET status = ?, endedAt = ? WHERE id = ? AND status = ?", operationInterrupted, formatTime(endedAt), record.ID, operationRunning)
+		if err != nil {
+			return interrupted, err
+		}
+		if updated, err := result.RowsAffected(); err == nil {
+			interrupted += int(updated)
+		}
+	}
+	return interrupted, nil
+}
+
+// Format a time to be saved in the database, the zero time is saved as an empty string
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// Parse a time saved by formatTime, invalid values are returned as the zero time
+func parseTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func (s *sqliteEnvironmentStore) Close() error {
+	return s.db.Close()
+}
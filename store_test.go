@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// The stores to run the same tests on
+func testStores(t *testing.T) map[string]EnvironmentStore {
+	sqliteStore, err := newSQLiteEnvironmentStore(filepath.Join(t.TempDir(), "environments.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqliteStore.Close() })
+	return map[string]EnvironmentStore{
+		"memory": newMemoryEnvironmentStore(),
+		"sqlite": sqliteStore,
+	}
+}
+
+func TestReservePorts(t *testing.T) {
+	// env1 holds 32000 and 33000 before each case
+	tests := []struct {
+		name          string
+		environmentId string
+		reservations  []PortReservation
+		conflict      bool
+		// The ports reserved by each environment after the case
+		want map[string][]int
+	}{
+		{
+			name:          "free ports",
+			environmentId: "env2",
+			reservations:  []PortReservation{{Port: 34000, Variable: "DATA_PORTAL_PORT"}, {Port: 35000, Variable: "API_PORT"}},
+			want:          map[string][]int{"env1": {32000, 33000}, "env2": {34000, 35000}},
+		},
+		{
+			name:          "port of another environment",
+			environmentId: "env2",
+			reservations:  []PortReservation{{Port: 34000, Variable: "DATA_PORTAL_PORT"}, {Port: 33000, Variable: "API_PORT"}},
+			conflict:      true,
+			want:          map[string][]int{"env1": {32000, 33000}},
+		},
+		{
+			name:          "same port twice",
+			environmentId: "env2",
+			reservations:  []PortReservation{{Port: 34000, Variable: "DATA_PORTAL_PORT"}, {Port: 34000, Variable: "API_PORT"}},
+			conflict:      true,
+			want:          map[string][]int{"env1": {32000, 33000}},
+		},
+		{
+			name:          "edited environment keeps one of its ports",
+			environmentId: "env1",
+			reservations:  []PortReservation{{Port: 33000, Variable: "DATA_PORTAL_PORT"}, {Port: 36000, Variable: "API_PORT"}},
+			want:          map[string][]int{"env1": {33000, 36000}},
+		},
+		{
+			name:          "release the ports",
+			environmentId: "env1",
+			want:          map[string][]int{},
+		},
+	}
+
+	for _, test := range tests {
+		for storeName, store := range testStores(t) {
+			t.Run(test.name+"/"+storeName, func(t *testing.T) {
+				err := store.ReservePorts("env1", []PortReservation{{Port: 32000, Variable: "DATA_PORTAL_PORT"}, {Port: 33000, Variable: "API_PORT"}})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				err = store.ReservePorts(test.environmentId, test.reservations)
+				if test.conflict != errors.Is(err, errPortReserved) {
+					t.Fatalf("ReservePorts() = %v, want a conflict: %t", err, test.conflict)
+				}
+				if !test.conflict && err != nil {
+					t.Fatal(err)
+				}
+
+				reservations, err := store.GetPortReservations()
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := make(map[string][]int)
+				for _, reservation := range reservations {
+					got[reservation.EnvironmentID] = append(got[reservation.EnvironmentID], reservation.Port)
+				}
+				if !reflect.DeepEqual(got, test.want) {
+					t.Errorf("reserved ports = %v, want %v", got, test.want)
+				}
+			})
+		}
+	}
+}
+
+// Deleting an environment releases its ports
+func TestDeleteEnvironmentReleasesPorts(t *testing.T) {
+	for storeName, store := range testStores(t) {
+		t.Run(storeName, func(t *testing.T) {
+			environment := Environment{ID: "env1", Platform: "docker", EnvironmentSetup: EnvironmentSetup{Name: "env", Version: "1.0"}}
+			if err := store.SaveEnvironment(environment); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.ReservePorts("env1", []PortReservation{{Port: 32000, Variable: "DATA_PORTAL_PORT"}}); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.DeleteEnvironment("env1"); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.ReservePorts("env2", []PortReservation{{Port: 32000, Variable: "DATA_PORTAL_PORT"}}); err != nil {
+				t.Errorf("the port of the deleted environment is still reserved: %v", err)
+			}
+		})
+	}
+}
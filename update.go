@@ -3,7 +3,6 @@ package main
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"strings"
 
 	"github.com/minio/selfupdate"
@@ -21,6 +20,9 @@ RWQFPHbNOOkG4bXw9P9+wzRhQLwNcBZdgn94TCJyaY7e7CyBYzXXXktB`
 
 // Check if there is a new version of the app available and return the url to download it, empty string if there is no update
 func (a *App) CheckForUpdates() bool {
+	if a.isOfflineMode() {
+		return false
+	}
 	// Check if the latest version is greater than the current version
 	return isGreaterVersion(getLatestVersion(), VERSION)
 }
@@ -44,7 +46,7 @@ func (a *App) DoUpdate() error {
 	fmt.Println("Signature: ", signatureUrl)
 
 	// Load the binary from the URL
-	resp, err := http.Get(binaryUrl)
+	resp, err := networkHTTPClient().Get(binaryUrl)
 	if err != nil {
 		return err
 	}
@@ -71,7 +73,7 @@ func (a *App) DoUpdate() error {
 	err = verifier.LoadFromURL(
 		signatureUrl, // URL of the signature
 		PUBLIC_KEY,   // Public key
-		http.DefaultTransport,
+		networkTransport(),
 	)
 	if err != nil {
 		return err
@@ -205,7 +207,7 @@ func (a *App) GetReleaseUrl() (string, error) {
 
 // Get the latest release from the GitHub repository
 func getLatestRelease() (*github.RepositoryRelease, error) {
-	client := github.NewClient(nil)
+	client := github.NewClient(networkHTTPClient())
 	release, _, err := client.Repositories.GetLatestRelease(context.Background(), "epos-eu", "opensource-desktop")
 	if err != nil {
 		return nil, err
@@ -0,0 +1,323 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The types, constraints and cross-field rules of the variables of the env files, shared by all the platforms.
+// The variables that are not in the schema are not checked.
+//
+//go:embed schemas/variables.json
+var variablesSchemaFile []byte
+
+// The definition of a variable in the schema
+type variableDefinition struct {
+	// string, integer, boolean, port or url
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+	// Required only when another variable has the given value
+	RequiredWhen *struct {
+		Variable string `json:"variable"`
+		Value    string `json:"value"`
+	} `json:"requiredWhen"`
+	// The range of the integer and port variables
+	Min *int `json:"min"`
+	Max *int `json:"max"`
+	// A regular expression the value must match, and the message shown when it doesn't
+	Pattern string `json:"pattern"`
+	Message string `json:"message"`
+	// The allowed values
+	Enum []string `json:"enum"`
+
+	patternRegexp *regexp.Regexp
+}
+
+// A rule on several variables
+type variableRule struct {
+	// unique: the variables whose name match pattern must have different values
+	// ordered: the values of the variables must be in ascending order
+	Rule      string   `json:"rule"`
+	Pattern   string   `json:"pattern"`
+	Variables []string `json:"variables"`
+	// The message of the error, %s is replaced with the other variable
+	Message string `json:"message"`
+
+	patternRegexp *regexp.Regexp
+}
+
+type variablesSchema struct {
+	Variables map[string]*variableDefinition `json:"variables"`
+	// Definitions of the variables whose name match the pattern, used for the variables not in Variables
+	Patterns []struct {
+		Pattern    string              `json:"pattern"`
+		Definition *variableDefinition `json:"definition"`
+
+		patternRegexp *regexp.Regexp
+	} `json:"patterns"`
+	Rules []*variableRule `json:"rules"`
+}
+
+// VariableError describes why the value of a variable is not valid
+type VariableError struct {
+	Section  string `json:"section"`
+	Variable string `json:"variable"`
+	Message  string `json:"message"`
+}
+
+// Returned by InstallEnvironment when some variables are not valid
+type invalidVariablesError struct {
+	errors []VariableError
+}
+
+func (e invalidVariablesError) Error() string {
+	var messages []string
+	for _, variableError := range e.errors {
+		messages = append(messages, variableError.Variable+" "+variableError.Message)
+	}
+	return "invalid variables: " + strings.Join(messages, "; ")
+}
+
+var (
+	loadedVariablesSchema    *variablesSchema
+	loadedVariablesSchemaErr error
+	loadVariablesSchemaOnce  sync.Once
+)
+
+// Get the schema of the variables, parsed the first time
+func getVariablesSchema() (*variablesSchema, error) {
+	loadVariablesSchemaOnce.Do(func() {
+		loadedVariablesSchema, loadedVariablesSchemaErr = parseVariablesSchema(variablesSchemaFile)
+	})
+	return loadedVariablesSchema, loadedVariablesSchemaErr
+}
+
+func parseVariablesSchema(data []byte) (*variablesSchema, error) {
+	var schema variablesSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid variables schema: %w", err)
+	}
+
+	var err error
+	for name, definition := range schema.Variables {
+		if definition.patternRegexp, err = compileSchemaPattern(definition.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern of %s in the variables schema: %w", name, err)
+		}
+	}
+	for i := range schema.Patterns {
+		pattern := &schema.Patterns[i]
+		if pattern.patternRegexp, err = compileSchemaPattern(pattern.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern %s in the variables schema: %w", pattern.Pattern, err)
+		}
+		if pattern.Definition.patternRegexp, err = compileSchemaPattern(pattern.Definition.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern of %s in the variables schema: %w", pattern.Pattern, err)
+		}
+	}
+	for _, rule := range schema.Rules {
+		if rule.patternRegexp, err = compileSchemaPattern(rule.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern of the %s rule in the variables schema: %w", rule.Rule, err)
+		}
+	}
+	return &schema, nil
+}
+
+// Compile the pattern, an empty pattern is returned as nil
+func compileSchemaPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+// Get the definition of a variable, nil if it is not in the schema
+func (s *variablesSchema) definition(name string) *variableDefinition {
+	if definition, ok := s.Variables[name]; ok {
+		return definition
+	}
+	for _, pattern := range s.Patterns {
+		if pattern.patternRegexp.MatchString(name) {
+			return pattern.Definition
+		}
+	}
+	return nil
+}
+
+// Check the variables of an environment before installing it, returns an empty list if they are all valid
+func (a *App) ValidateVariables(platform string, variables []Section) ([]VariableError, error) {
+	if _, err := getPlatformDriver(platform); err != nil {
+		return nil, fmt.Errorf("platform not supported: %s", platform)
+	}
+	schema, err := getVariablesSchema()
+	if err != nil {
+		return nil, err
+	}
+	return schema.validate(variables), nil
+}
+
+// Check the variables against the schema, the errors are sorted by section and variable
+func (s *variablesSchema) validate(sections []Section) []VariableError {
+	// The section of each variable, and all the values to check the conditions and the rules
+	sectionOf := make(map[string]string)
+	values := make(map[string]string)
+	for _, section := range sections {
+		for name, value := range section.Variables {
+			sectionOf[name] = section.Name
+			values[name] = strings.TrimSpace(value)
+		}
+	}
+
+	errors := []VariableError{}
+	addError := func(name, message string) {
+		errors = append(errors, VariableError{Section: sectionOf[name], Variable: name, Message: message})
+	}
+
+	for name, value := range values {
+		definition := s.definition(name)
+		if definition == nil {
+			continue
+		}
+		// The secrets of an environment being edited are sent masked, the stored values are kept (see unmaskSecrets)
+		if value == secretMask && isSecretVariable(name) {
+			continue
+		}
+		if message := definition.check(value, values); message != "" {
+			addError(name, message)
+		}
+	}
+
+	for _, rule := range s.Rules {
+		for name, message := range rule.check(values) {
+			addError(name, message)
+		}
+	}
+
+	sort.Slice(errors, func(i, j int) bool {
+		if errors[i].Section != errors[j].Section {
+			return errors[i].Section < errors[j].Section
+		}
+		if errors[i].Variable != errors[j].Variable {
+			return errors[i].Variable < errors[j].Variable
+		}
+		return errors[i].Message < errors[j].Message
+	})
+	return errors
+}
+
+// Check a value against the definition, returns the error message or "" if it is valid
+func (d *variableDefinition) check(value string, values map[string]string) string {
+	if value == "" {
+		if d.Required {
+			return "is required"
+		}
+		if d.RequiredWhen != nil && values[d.RequiredWhen.Variable] == d.RequiredWhen.Value {
+			return fmt.Sprintf("is required when %s is %s", d.RequiredWhen.Variable, d.RequiredWhen.Value)
+		}
+		return ""
+	}
+
+	switch d.Type {
+	case "integer", "port":
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return "must be a number"
+		}
+		min, max := d.Min, d.Max
+		if d.Type == "port" {
+			if min == nil {
+				min = intPointer(1)
+			}
+			if max == nil {
+				max = intPointer(65535)
+			}
+		}
+		if min != nil && number < *min || max != nil && number > *max {
+			return rangeMessage(min, max)
+		}
+	case "boolean":
+		if value != "true" && value != "false" {
+			return "must be true or false"
+		}
+	case "url":
+		parsed, err := url.Parse(value)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return "must be an http or https URL"
+		}
+	}
+
+	if len(d.Enum) > 0 && !slices.Contains(d.Enum, value) {
+		return "must be one of " + strings.Join(d.Enum, ", ")
+	}
+	if d.patternRegexp != nil && !d.patternRegexp.MatchString(value) {
+		if d.Message != "" {
+			return d.Message
+		}
+		return "must match " + d.Pattern
+	}
+	return ""
+}
+
+// Check the rule on all the values, returns the error messages by variable
+func (r *variableRule) check(values map[string]string) map[string]string {
+	errors := make(map[string]string)
+
+	switch r.Rule {
+	case "unique":
+		// Group the variables by value
+		byValue := make(map[string][]string)
+		for name, value := range values {
+			if value != "" && r.patternRegexp.MatchString(name) {
+				byValue[value] = append(byValue[value], name)
+			}
+		}
+		for _, names := range byValue {
+			if len(names) < 2 {
+				continue
+			}
+			sort.Strings(names)
+			for i, name := range names {
+				// The error refers to one of the other variables with the same value
+				other := names[0]
+				if i == 0 {
+					other = names[1]
+				}
+				errors[name] = fmt.Sprintf(r.Message, other)
+			}
+		}
+	case "ordered":
+		for i := 0; i+1 < len(r.Variables); i++ {
+			current, errCurrent := strconv.Atoi(values[r.Variables[i]])
+			next, errNext := strconv.Atoi(values[r.Variables[i+1]])
+			// Missing or invalid values are reported by the definitions
+			if errCurrent != nil || errNext != nil {
+				continue
+			}
+			if current > next {
+				errors[r.Variables[i]] = fmt.Sprintf(r.Message, r.Variables[i+1])
+			}
+		}
+	}
+	return errors
+}
+
+func rangeMessage(min, max *int) string {
+	switch {
+	case min != nil && max != nil:
+		return fmt.Sprintf("must be between %d and %d", *min, *max)
+	case min != nil:
+		return fmt.Sprintf("must be at least %d", *min)
+	default:
+		return fmt.Sprintf("must be at most %d", *max)
+	}
+}
+
+func intPointer(value int) *int {
+	return &value
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+)
+
+// The env.env files of the platforms are valid as they are
+func TestValidateVariablesDefaults(t *testing.T) {
+	a := &App{store: newMemoryEnvironmentStore()}
+	for _, platform := range []string{"docker", "kubernetes", "podman"} {
+		t.Run(platform, func(t *testing.T) {
+			defaults, err := a.ReadEnvVariables(platform)
+			if err != nil {
+				t.Fatal(err)
+			}
+			errors, err := a.ValidateVariables(platform, defaults)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(errors) != 0 {
+				t.Errorf("the defaults of %s are not valid: %+v", platform, errors)
+			}
+		})
+	}
+}
+
+func TestValidateVariables(t *testing.T) {
+	a := &App{store: newMemoryEnvironmentStore()}
+	defaults, err := a.ReadEnvVariables("docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		changes map[string]string
+		// Mask the secrets first, like they are sent by the frontend when an environment is edited
+		masked bool
+		// The variables that must have an error
+		invalid []string
+	}{
+		{name: "port out of range", changes: map[string]string{"API_PORT": "70000"}, invalid: []string{"API_PORT"}},
+		{name: "port not a number", changes: map[string]string{"DATA_PORTAL_PORT": "http"}, invalid: []string{"DATA_PORTAL_PORT"}},
+		{name: "same port twice", changes: map[string]string{"API_PORT": "35000", "DATA_PORTAL_PORT": "35000"}, invalid: []string{"DATA_PORTAL_PORT"}},
+		{name: "required value empty", changes: map[string]string{"POSTGRESQL_PASSWORD": " "}, invalid: []string{"POSTGRESQL_PASSWORD"}},
+		{name: "path without slash", changes: map[string]string{"API_PATH": "api/v1"}, invalid: []string{"API_PATH"}},
+		{name: "pool sizes not ordered", changes: map[string]string{"CONNECTION_POOL_MIN_SIZE": "50", "CONNECTION_POOL_INIT_SIZE": "10"}, invalid: []string{"CONNECTION_POOL_MIN_SIZE"}},
+		{name: "secret masked by the frontend", changes: map[string]string{"POSTGRESQL_PASSWORD": secretMask}},
+		{name: "all the secrets masked", masked: true},
+		{name: "masked secrets and an invalid port", masked: true, changes: map[string]string{"API_PORT": "70000"}, invalid: []string{"API_PORT"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			variables := copySections(defaults)
+			if test.masked {
+				variables = maskSecrets(defaults)
+			}
+			for _, section := range variables {
+				for name, value := range test.changes {
+					if _, ok := section.Variables[name]; ok {
+						section.Variables[name] = value
+					}
+				}
+			}
+
+			errors, err := a.ValidateVariables("docker", variables)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := make(map[string]bool)
+			for _, variableError := range errors {
+				found[variableError.Variable] = true
+			}
+			for _, name := range test.invalid {
+				if !found[name] {
+					t.Errorf("no error for %s in %+v", name, errors)
+				}
+			}
+			if len(test.invalid) == 0 && len(errors) != 0 {
+				t.Errorf("unexpected errors %+v", errors)
+			}
+		})
+	}
+}
+
+func TestValidateVariablesUnknownPlatform(t *testing.T) {
+	a := &App{store: newMemoryEnvironmentStore()}
+	if _, err := a.ValidateVariables("vagrant", nil); err == nil {
+		t.Error("expected an error for an unknown platform")
+	}
+}